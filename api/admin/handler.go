@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/logger"
+	"github.com/robdimsdale/tardy/middleware"
+)
+
+type Handler interface {
+	GetLogLevel(w http.ResponseWriter, r *http.Request)
+	PutLogLevel(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger       lager.Logger
+	sink         *lager.ReconfigurableSink
+	adminUserIDs map[uint]bool
+}
+
+// NewHandler returns a handler for administering tardy at runtime.
+// Only the users in adminUserIDs may use it; if there are none,
+// every request is forbidden.
+func NewHandler(
+	logger lager.Logger,
+	sink *lager.ReconfigurableSink,
+	adminUserIDs []uint,
+) Handler {
+	admins := map[uint]bool{}
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
+	return &handler{
+		logger:       logger.Session("api-v1-admin"),
+		sink:         sink,
+		adminUserIDs: admins,
+	}
+}
+
+type logLevel struct {
+	Level logger.LogLevel `json:"level"`
+}
+
+// GetLogLevel returns the current minimum log level.
+func (h handler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	_, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeLogLevel(w)
+}
+
+// PutLogLevel sets the minimum log level from the JSON request body.
+func (h handler) PutLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	var req logLevel
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Info("invalid log level request", lager.Data{"error": err.Error()})
+		http.Error(w, fmt.Sprintf("invalid log level request: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	level, err := logger.LagerLogLevel(req.Level)
+	if err != nil {
+		h.logger.Info("invalid log level request", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := h.admin(w, r)
+	if !ok {
+		return
+	}
+
+	h.sink.SetMinLevel(level)
+
+	// Logged at error level so that it is seen at any level but fatal.
+	h.logger.Error("log-level-changed", nil, lager.Data{"user-id": userID, "level": req.Level})
+	h.writeLogLevel(w)
+}
+
+// admin returns the ID of the admin who authenticated the request.
+// If the user is not an admin an error is written to w and false returned.
+func (h handler) admin(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		err := fmt.Errorf("user not found in request")
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), 500)
+		return 0, false
+	}
+
+	if !h.adminUserIDs[userID] {
+		h.logger.Info("admin access denied", lager.Data{"user-id": userID, "url": r.URL.Path})
+		http.Error(w, "admin access required", http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func (h handler) writeLogLevel(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(logLevel{Level: logger.Level(h.sink.GetMinLevel())})
+	if err != nil {
+		h.logger.Error("failed to serialize log level", err)
+	}
+}
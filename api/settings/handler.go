@@ -0,0 +1,117 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/store"
+)
+
+type Handler interface {
+	Get(w http.ResponseWriter, r *http.Request)
+	Put(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger       lager.Logger
+	userStore    store.Store
+	defaultRules lateness.Rules
+}
+
+func NewHandler(
+	logger lager.Logger,
+	userStore store.Store,
+	defaultRules lateness.Rules,
+) Handler {
+	return &handler{
+		logger:       logger.Session("api-v1-settings"),
+		userStore:    userStore,
+		defaultRules: defaultRules,
+	}
+}
+
+// Get returns the logged-in user's settings.
+func (h handler) Get(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.userStore.Load(userID)
+	if err != nil {
+		h.logger.Error("failed to load settings", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	h.writeSettings(w, data.Settings)
+}
+
+// Put replaces the logged-in user's settings with the JSON request body.
+// An empty lateness_mode restores the server's default.
+func (h handler) Put(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	var settings store.Settings
+	err := json.NewDecoder(r.Body).Decode(&settings)
+	if err != nil {
+		h.logger.Info("invalid settings", lager.Data{"error": err.Error()})
+		http.Error(w, fmt.Sprintf("invalid settings: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Settings are checked against the server's defaults, which they
+	// are combined with, so that they cannot break later requests.
+	err = settings.Apply(h.defaultRules).Validate()
+	if err != nil {
+		h.logger.Info("invalid settings", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	err = h.userStore.Update(userID, func(data *store.UserData) error {
+		data.Settings = settings
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("failed to save settings", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	h.logger.Info("settings-updated", lager.Data{"user-id": userID, "settings": settings})
+	h.writeSettings(w, settings)
+}
+
+// userID returns the ID of the user who authenticated the request.
+// If it cannot be determined an error is written to w and false returned.
+func (h handler) userID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		err := fmt.Errorf("user not found in request")
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), 500)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func (h handler) writeSettings(w http.ResponseWriter, settings store.Settings) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		h.logger.Error("failed to serialize settings", err)
+	}
+}
@@ -0,0 +1,202 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/api/tasks"
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/store"
+)
+
+type Handler interface {
+	Stats(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger            lager.Logger
+	taskSourceFactory tardy.TaskSourceFactory
+	userStore         store.Store
+	defaultRules      lateness.Rules
+}
+
+func NewHandler(
+	logger lager.Logger,
+	taskSourceFactory tardy.TaskSourceFactory,
+	userStore store.Store,
+	defaultRules lateness.Rules,
+) Handler {
+	return &handler{
+		logger:            logger.Session("api-v1-stats"),
+		taskSourceFactory: taskSourceFactory,
+		userStore:         userStore,
+		defaultRules:      defaultRules,
+	}
+}
+
+type response struct {
+	Overall tardy.Stats `json:"overall"`
+	GroupBy string      `json:"group_by,omitempty"`
+	Groups  []group     `json:"groups,omitempty"`
+}
+
+type group struct {
+	Key   string      `json:"key"`
+	Label string      `json:"label"`
+	Stats tardy.Stats `json:"stats"`
+}
+
+// Stats returns lateness statistics for the completed tasks matching
+// the same query parameters as the tasks endpoint, optionally grouped
+// by the group_by parameter.
+func (h handler) Stats(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupers[groupBy] == nil {
+		err := fmt.Errorf("group_by must be one of list, week, month, weekday or assignee, got %q", groupBy)
+		h.logger.Info("invalid query", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, ok := tasks.ParseQuery(w, r, h.logger, h.taskSourceFactory, h.userStore, h.defaultRules)
+	if !ok {
+		return
+	}
+
+	matched, ok := query.CompletedTasks(w, h.logger)
+	if !ok {
+		return
+	}
+
+	resp := response{
+		Overall: tardy.NewStats(matched),
+		GroupBy: groupBy,
+	}
+	if groupBy != "" {
+		resp.Groups = groupStats(matched, groupers[groupBy])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		h.logger.Error("failed to serialize stats", err)
+	}
+}
+
+// groupStats returns the statistics of each group of tasks, in order.
+func groupStats(tasks []tardy.Task, g *grouper) []group {
+	grouped := map[string][]tardy.Task{}
+	labels := map[string]string{}
+	for _, t := range tasks {
+		key := g.key(t)
+		grouped[key] = append(grouped[key], t)
+		labels[key] = g.label(t)
+	}
+
+	groups := []group{}
+	for key, groupTasks := range grouped {
+		groups = append(groups, group{
+			Key:   key,
+			Label: labels[key],
+			Stats: tardy.NewStats(groupTasks),
+		})
+	}
+	sort.Sort(groupsByKey{groups: groups, less: g.less})
+
+	return groups
+}
+
+type grouper struct {
+	key   func(tardy.Task) string
+	label func(tardy.Task) string
+	less  func(a, b string) bool
+}
+
+func lexicalLess(a, b string) bool {
+	return a < b
+}
+
+func numericLess(a, b string) bool {
+	x, _ := strconv.ParseUint(a, 10, 0)
+	y, _ := strconv.ParseUint(b, 10, 0)
+	return x < y
+}
+
+func idKey(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// groupers are keyed by the supported values of the group_by parameter.
+// Date-based groups use the task's due date.
+var groupers = map[string]*grouper{
+	"list": {
+		key:   func(t tardy.Task) string { return idKey(t.ListID) },
+		label: func(t tardy.Task) string { return t.ListTitle },
+		less:  numericLess,
+	},
+	"assignee": {
+		key: func(t tardy.Task) string { return idKey(t.AssigneeID) },
+		label: func(t tardy.Task) string {
+			if t.AssigneeID == 0 {
+				return "Unassigned"
+			}
+			return t.AssigneeName
+		},
+		less: numericLess,
+	},
+	"week": {
+		key:   weekKey,
+		label: weekKey,
+		less:  lexicalLess,
+	},
+	"month": {
+		key:   monthKey,
+		label: monthKey,
+		less:  lexicalLess,
+	},
+	"weekday": {
+		key:   weekdayKey,
+		label: weekdayKey,
+		// Weeks start on Monday, as for ISO weeks.
+		less: func(a, b string) bool { return weekdayIndex(a) < weekdayIndex(b) },
+	},
+}
+
+func weekKey(t tardy.Task) string {
+	year, week := t.DueDate.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+func monthKey(t tardy.Task) string {
+	return t.DueDate.Format("2006-01")
+}
+
+func weekdayKey(t tardy.Task) string {
+	return t.DueDate.Weekday().String()
+}
+
+func weekdayIndex(name string) int {
+	for i, d := range []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"} {
+		if d == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type groupsByKey struct {
+	groups []group
+	less   func(a, b string) bool
+}
+
+func (g groupsByKey) Len() int           { return len(g.groups) }
+func (g groupsByKey) Less(i, j int) bool { return g.less(g.groups[i].Key, g.groups[j].Key) }
+func (g groupsByKey) Swap(i, j int)      { g.groups[i], g.groups[j] = g.groups[j], g.groups[i] }
@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+)
+
+func TestGroupStats(t *testing.T) {
+	tasks := []tardy.Task{
+		{ListID: 10, ListTitle: "Work", AssigneeID: 2, AssigneeName: "Sam", DueDate: time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC), Days: 2},
+		{ListID: 2, ListTitle: "Home", DueDate: time.Date(2016, 1, 3, 0, 0, 0, 0, time.UTC), Days: -1},
+		{ListID: 10, ListTitle: "Work", AssigneeID: 2, AssigneeName: "Sam", DueDate: time.Date(2016, 2, 1, 0, 0, 0, 0, time.UTC), Days: 0},
+	}
+
+	for _, test := range []struct {
+		groupBy string
+		keys    []string
+		labels  []string
+		counts  []int
+	}{
+		// Numeric keys sort numerically rather than lexically.
+		{"list", []string{"2", "10"}, []string{"Home", "Work"}, []int{1, 2}},
+		{"assignee", []string{"0", "2"}, []string{"Unassigned", "Sam"}, []int{1, 2}},
+		// Sunday 3 January 2016 is in the last ISO week of 2015.
+		{"week", []string{"2015-W53", "2016-W01", "2016-W05"}, []string{"2015-W53", "2016-W01", "2016-W05"}, []int{1, 1, 1}},
+		{"month", []string{"2016-01", "2016-02"}, []string{"2016-01", "2016-02"}, []int{2, 1}},
+		{"weekday", []string{"Monday", "Sunday"}, []string{"Monday", "Sunday"}, []int{2, 1}},
+	} {
+		groups := groupStats(tasks, groupers[test.groupBy])
+
+		var keys, labels []string
+		var counts []int
+		for _, g := range groups {
+			keys = append(keys, g.Key)
+			labels = append(labels, g.Label)
+			counts = append(counts, g.Stats.Count)
+		}
+
+		if !reflect.DeepEqual(keys, test.keys) || !reflect.DeepEqual(labels, test.labels) || !reflect.DeepEqual(counts, test.counts) {
+			t.Errorf("group_by %s: expected keys %v, labels %v and counts %v, got %v, %v and %v",
+				test.groupBy, test.keys, test.labels, test.counts, keys, labels, counts)
+		}
+	}
+
+	groups := groupStats(tasks, groupers["list"])
+	if groups[1].Stats.LateCount != 1 || groups[1].Stats.OnTimeCount != 1 {
+		t.Errorf("expected each group's stats to cover only its tasks, got %+v", groups[1].Stats)
+	}
+}
@@ -0,0 +1,24 @@
+package tasks
+
+import (
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/lateness"
+)
+
+// NewConverter returns a converter labelling tasks with the names of the
+// task source's lists and users. Names are only used to label tasks, so
+// tasks are still converted, without them, if they cannot be fetched.
+func NewConverter(logger lager.Logger, taskSource tardy.TaskSource, rules lateness.Rules) tardy.Converter {
+	lists, err := taskSource.Lists()
+	if err != nil {
+		logger.Error("failed to get lists", err)
+	}
+
+	users, err := taskSource.Users()
+	if err != nil {
+		logger.Error("failed to get users", err)
+	}
+
+	return tardy.NewConverter(lists, users, rules)
+}
@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/middleware"
+)
+
+var exportHeader = []string{
+	"id",
+	"title",
+	"list_id",
+	"list_title",
+	"assignee_id",
+	"assignee_name",
+	"starred",
+	"recurrence_type",
+	"created_at",
+	"due_date",
+	"completed_at",
+	"days",
+	"lateness_seconds",
+}
+
+// Export returns the same completed tasks as Tasks as a CSV file,
+// for use in spreadsheets.
+func (h handler) Export(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	query, ok := ParseQuery(w, r, h.logger, h.taskSourceFactory, h.userStore, h.defaultRules)
+	if !ok {
+		return
+	}
+
+	tasks, ok := query.CompletedTasks(w, h.logger)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="tardy-tasks.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write(exportHeader)
+	for _, t := range tasks {
+		cw.Write(exportRecord(t))
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		h.logger.Error("failed to write csv", err)
+	}
+}
+
+func exportRecord(t tardy.Task) []string {
+	return []string{
+		strconv.FormatUint(uint64(t.ID), 10),
+		t.Title,
+		strconv.FormatUint(uint64(t.ListID), 10),
+		t.ListTitle,
+		strconv.FormatUint(uint64(t.AssigneeID), 10),
+		t.AssigneeName,
+		strconv.FormatBool(t.Starred),
+		t.RecurrenceType,
+		exportTime(t.CreatedAt),
+		t.DueDate.Format("2006-01-02"),
+		exportTime(t.CompletedAt),
+		strconv.Itoa(t.Days),
+		strconv.FormatInt(t.LatenessSeconds, 10),
+	}
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
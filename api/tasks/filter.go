@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+)
+
+const dateFormat = "2006-01-02"
+
+// Filter restricts the tasks returned by the API.
+// The zero value matches every task.
+type Filter struct {
+	dueFrom       time.Time
+	dueTo         time.Time
+	completedFrom time.Time
+	completedTo   time.Time
+
+	listIDs         map[uint]bool
+	assigneeIDs     map[uint]bool
+	starred         *bool
+	recurrenceTypes map[string]bool
+
+	minDays *int
+	maxDays *int
+}
+
+// validationError lists every problem found with a request,
+// so that clients can fix them all at once.
+type validationError []string
+
+func (e validationError) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// ParseFilter builds a filter from the query parameters:
+//
+//	due_from, due_to, completed_from, completed_to
+//	  inclusive bounds, as YYYY-MM-DD dates or RFC3339 timestamps
+//	list_id, assignee_id
+//	  repeatable or comma-separated IDs
+//	starred
+//	  true or false
+//	recurrence_type
+//	  repeatable or comma-separated; "none" matches non-recurring tasks
+//	min_days, max_days
+//	  inclusive bounds on lateness in days
+func ParseFilter(values url.Values) (Filter, error) {
+	var f Filter
+	var errs validationError
+
+	f.dueFrom = parseBound(values, "due_from", false, &errs)
+	f.dueTo = parseBound(values, "due_to", true, &errs)
+	f.completedFrom = parseBound(values, "completed_from", false, &errs)
+	f.completedTo = parseBound(values, "completed_to", true, &errs)
+
+	f.listIDs = parseIDs(values, "list_id", &errs)
+	f.assigneeIDs = parseIDs(values, "assignee_id", &errs)
+
+	if s := values.Get("starred"); s != "" {
+		starred, err := strconv.ParseBool(s)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("starred must be true or false, got %q", s))
+		} else {
+			f.starred = &starred
+		}
+	}
+
+	if types := splitValues(values, "recurrence_type"); len(types) > 0 {
+		f.recurrenceTypes = map[string]bool{}
+		for _, t := range types {
+			f.recurrenceTypes[t] = true
+		}
+	}
+
+	f.minDays = parseInt(values, "min_days", &errs)
+	f.maxDays = parseInt(values, "max_days", &errs)
+
+	if !f.dueFrom.IsZero() && !f.dueTo.IsZero() && f.dueTo.Before(f.dueFrom) {
+		errs = append(errs, "due_to must not be before due_from")
+	}
+
+	if !f.completedFrom.IsZero() && !f.completedTo.IsZero() && f.completedTo.Before(f.completedFrom) {
+		errs = append(errs, "completed_to must not be before completed_from")
+	}
+
+	if f.minDays != nil && f.maxDays != nil && *f.maxDays < *f.minDays {
+		errs = append(errs, "max_days must not be less than min_days")
+	}
+
+	if len(errs) > 0 {
+		return Filter{}, errs
+	}
+
+	return f, nil
+}
+
+// Matches reports whether the task satisfies every restriction in the filter.
+func (f Filter) Matches(task tardy.Task) bool {
+	if !inRange(task.DueDate, f.dueFrom, f.dueTo) ||
+		!inRange(task.CompletedAt, f.completedFrom, f.completedTo) {
+		return false
+	}
+
+	if f.listIDs != nil && !f.listIDs[task.ListID] {
+		return false
+	}
+
+	if f.assigneeIDs != nil && !f.assigneeIDs[task.AssigneeID] {
+		return false
+	}
+
+	if f.starred != nil && *f.starred != task.Starred {
+		return false
+	}
+
+	if f.recurrenceTypes != nil {
+		recurrenceType := task.RecurrenceType
+		if recurrenceType == "" {
+			recurrenceType = "none"
+		}
+		if !f.recurrenceTypes[recurrenceType] {
+			return false
+		}
+	}
+
+	if f.minDays != nil && task.Days < *f.minDays {
+		return false
+	}
+
+	if f.maxDays != nil && task.Days > *f.maxDays {
+		return false
+	}
+
+	return true
+}
+
+func inRange(t time.Time, from time.Time, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
+
+// parseBound parses a date or timestamp parameter.
+// A date used as an upper bound includes the whole of that day.
+func parseBound(values url.Values, name string, upper bool, errs *validationError) time.Time {
+	s := values.Get(name)
+	if s == "" {
+		return time.Time{}
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("%s must be a YYYY-MM-DD date or RFC3339 timestamp, got %q", name, s))
+		return time.Time{}
+	}
+
+	if upper {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t
+}
+
+func parseIDs(values url.Values, name string, errs *validationError) map[uint]bool {
+	strs := splitValues(values, name)
+	if len(strs) == 0 {
+		return nil
+	}
+
+	ids := map[uint]bool{}
+	for _, s := range strs {
+		id, err := strconv.ParseUint(s, 10, 0)
+		if err != nil {
+			*errs = append(*errs, fmt.Sprintf("%s must be a positive integer, got %q", name, s))
+			continue
+		}
+		ids[uint(id)] = true
+	}
+	return ids
+}
+
+func parseInt(values url.Values, name string, errs *validationError) *int {
+	s := values.Get(name)
+	if s == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("%s must be an integer, got %q", name, s))
+		return nil
+	}
+	return &i
+}
+
+// splitValues returns every value provided for the parameter,
+// splitting comma-separated values and ignoring empty ones.
+func splitValues(values url.Values, name string) []string {
+	var strs []string
+	for _, v := range values[name] {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s != "" {
+				strs = append(strs, s)
+			}
+		}
+	}
+	return strs
+}
@@ -0,0 +1,111 @@
+package tasks
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+)
+
+func mustParseFilter(t *testing.T, query string) Filter {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ParseFilter(values)
+	if err != nil {
+		t.Fatalf("failed to parse filter %q: %s", query, err)
+	}
+	return f
+}
+
+func TestFilterMatches(t *testing.T) {
+	task := tardy.Task{
+		ListID:         3,
+		AssigneeID:     7,
+		Starred:        true,
+		RecurrenceType: "",
+		DueDate:        time.Date(2016, 3, 10, 0, 0, 0, 0, time.UTC),
+		CompletedAt:    time.Date(2016, 3, 12, 18, 0, 0, 0, time.UTC),
+		Days:           2,
+	}
+
+	for _, test := range []struct {
+		query   string
+		matches bool
+	}{
+		{"", true},
+		{"due_from=2016-03-10&due_to=2016-03-10", true},
+		{"due_from=2016-03-11", false},
+		{"due_to=2016-03-09", false},
+		{"completed_to=2016-03-12", true},
+		{"completed_to=2016-03-12T17:00:00Z", false},
+		{"completed_from=2016-03-12T19:00:00%2B02:00", true},
+		{"list_id=1,3", true},
+		{"list_id=1&list_id=3", true},
+		{"list_id=1", false},
+		{"assignee_id=7", true},
+		{"assignee_id=8", false},
+		{"starred=true", true},
+		{"starred=false", false},
+		{"recurrence_type=none", true},
+		{"recurrence_type=week,month", false},
+		{"min_days=2&max_days=2", true},
+		{"min_days=3", false},
+		{"max_days=1", false},
+		{"list_id=3&starred=true&min_days=1&due_from=2016-03-01", true},
+		{"list_id=3&starred=false", false},
+	} {
+		f := mustParseFilter(t, test.query)
+		if f.Matches(task) != test.matches {
+			t.Errorf("filter %q: expected match to be %t", test.query, test.matches)
+		}
+	}
+
+	recurring := task
+	recurring.RecurrenceType = "week"
+	if !mustParseFilter(t, "recurrence_type=week").Matches(recurring) {
+		t.Errorf("expected recurrence_type to match recurring tasks")
+	}
+	if mustParseFilter(t, "recurrence_type=none").Matches(recurring) {
+		t.Errorf("expected recurrence_type=none not to match recurring tasks")
+	}
+}
+
+func TestParseFilterReportsEveryProblem(t *testing.T) {
+	values, err := url.ParseQuery("due_from=yesterday&list_id=1,x&assignee_id=-1&starred=maybe&min_days=a&completed_from=2016-03-02&completed_to=2016-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseFilter(values)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	for _, name := range []string{"due_from", "list_id", "assignee_id", "starred", "min_days", "completed_to"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to mention %s, got %q", name, err)
+		}
+	}
+}
+
+func TestParseFilterRejectsInvertedRanges(t *testing.T) {
+	for _, query := range []string{
+		"due_from=2016-03-02&due_to=2016-03-01",
+		"min_days=3&max_days=2",
+	} {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = ParseFilter(values)
+		if err == nil {
+			t.Errorf("expected an error parsing %q", query)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/middleware"
+)
+
+// Overdue returns the open tasks which are late right now, most overdue
+// first, with Days and LatenessSeconds measuring how overdue they are.
+// It accepts the same query parameters as Tasks.
+func (h handler) Overdue(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	query, ok := ParseQuery(w, r, h.logger, h.taskSourceFactory, h.userStore, h.defaultRules)
+	if !ok {
+		return
+	}
+
+	openTasks, err := query.TaskSource.OpenTasks()
+	if err != nil {
+		h.logger.Error("failed to get open tasks", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now()
+	overdue := []tardy.Task{}
+	for _, t := range openTasks {
+		tardyTask, ok := query.Converter.OverdueTask(t, now)
+		if ok && query.Filter.Matches(tardyTask) {
+			overdue = append(overdue, tardyTask)
+		}
+	}
+	sort.Sort(tasksBySeverity(overdue))
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(overdue)
+	if err != nil {
+		h.logger.Error("failed to serialize overdue tasks", err)
+	}
+}
+
+// tasksBySeverity sorts the most overdue tasks first,
+// breaking ties by ID for a stable order.
+type tasksBySeverity []tardy.Task
+
+func (t tasksBySeverity) Len() int      { return len(t) }
+func (t tasksBySeverity) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t tasksBySeverity) Less(i, j int) bool {
+	if t[i].Days != t[j].Days {
+		return t[i].Days > t[j].Days
+	}
+	if t[i].LatenessSeconds != t[j].LatenessSeconds {
+		return t[i].LatenessSeconds > t[j].LatenessSeconds
+	}
+	return t[i].ID < t[j].ID
+}
@@ -0,0 +1,91 @@
+package tasks
+
+import (
+	"net/http"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/store"
+	"github.com/robdimsdale/wl"
+)
+
+// Query is a request for the tasks of the user who authenticated it,
+// converted with the user's lateness rules and the request's overrides.
+type Query struct {
+	Filter     Filter
+	TaskSource tardy.TaskSource
+	Converter  tardy.Converter
+}
+
+// ParseQuery parses the filter and lateness rules of a request for
+// the user's tasks. If they are invalid, or the user's settings cannot
+// be loaded, an error is written to w and false returned.
+func ParseQuery(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger lager.Logger,
+	taskSourceFactory tardy.TaskSourceFactory,
+	userStore store.Store,
+	defaultRules lateness.Rules,
+) (Query, bool) {
+	values := r.URL.Query()
+
+	filter, err := ParseFilter(values)
+	if err != nil {
+		logger.Info("invalid query", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return Query{}, false
+	}
+
+	taskSource, userID, ok := middleware.TaskSource(w, r, logger, taskSourceFactory)
+	if !ok {
+		return Query{}, false
+	}
+
+	userRules, err := UserRules(userStore, userID, defaultRules)
+	if err != nil {
+		logger.Error("failed to get user settings", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return Query{}, false
+	}
+
+	rules, err := ParseRules(values, userRules)
+	if err != nil {
+		logger.Info("invalid query", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return Query{}, false
+	}
+
+	return Query{
+		Filter:     filter,
+		TaskSource: taskSource,
+		Converter:  NewConverter(logger, taskSource, rules),
+	}, true
+}
+
+// CompletedTasks returns the user's completed tasks which match the
+// filter. If they cannot be fetched, an error is written to w and false
+// returned.
+func (q Query) CompletedTasks(w http.ResponseWriter, logger lager.Logger) ([]tardy.Task, bool) {
+	completedTasks, err := q.TaskSource.CompletedTasks()
+	if err != nil {
+		logger.Error("failed to get completed tasks", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return nil, false
+	}
+
+	return tardyTasks(q.Converter, completedTasks, q.Filter), true
+}
+
+func tardyTasks(converter tardy.Converter, wlTasks []wl.Task, f Filter) []tardy.Task {
+	tasks := []tardy.Task{}
+	for _, t := range wlTasks {
+		tardyTask, ok := converter.Task(t)
+		if ok && f.Matches(tardyTask) {
+			tasks = append(tasks, tardyTask)
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,104 @@
+package tasks
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/store"
+)
+
+// ParseRules overrides the provided default lateness rules
+// with any of the query parameters:
+//
+//	tz
+//	  IANA time zone name, e.g. Europe/London
+//	deadline
+//	  time of day as HH:MM, from 00:00 to 24:00
+//	grace_period
+//	  duration, e.g. 90m
+//	resolution
+//	  day or hour
+//	mode
+//	  calendar or business
+//	weekend
+//	  comma-separated weekday names, used by business mode
+func ParseRules(values url.Values, defaults lateness.Rules) (lateness.Rules, error) {
+	rules := defaults
+	var errs validationError
+
+	if tz := values.Get("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("tz must be a time zone name, got %q", tz))
+		} else {
+			rules.Location = location
+		}
+	}
+
+	if s := values.Get("deadline"); s != "" {
+		deadline, err := lateness.ParseDeadline(s)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			rules.Deadline = deadline
+		}
+	}
+
+	if s := values.Get("grace_period"); s != "" {
+		gracePeriod, err := time.ParseDuration(s)
+		if err != nil || gracePeriod < 0 {
+			errs = append(errs, fmt.Sprintf("grace_period must be a non-negative duration, got %q", s))
+		} else {
+			rules.GracePeriod = gracePeriod
+		}
+	}
+
+	if s := values.Get("resolution"); s != "" {
+		rules.Resolution = lateness.Resolution(s)
+		if rules.Resolution != lateness.ResolutionDay && rules.Resolution != lateness.ResolutionHour {
+			errs = append(errs, fmt.Sprintf("resolution must be %s or %s, got %q", lateness.ResolutionDay, lateness.ResolutionHour, s))
+		}
+	}
+
+	if s := values.Get("mode"); s != "" {
+		rules.Mode = lateness.Mode(s)
+		if rules.Mode != lateness.ModeCalendar && rules.Mode != lateness.ModeBusiness {
+			errs = append(errs, fmt.Sprintf("mode must be %s or %s, got %q", lateness.ModeCalendar, lateness.ModeBusiness, s))
+		}
+	}
+
+	if _, ok := values["weekend"]; ok {
+		weekend, err := lateness.ParseWeekend(values.Get("weekend"))
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			rules.Weekend = weekend
+		}
+	}
+
+	if len(errs) > 0 {
+		return lateness.Rules{}, errs
+	}
+
+	// Individually valid parameters may still combine into invalid rules,
+	// e.g. business mode with hour resolution.
+	err := rules.Validate()
+	if err != nil {
+		return lateness.Rules{}, err
+	}
+
+	return rules, nil
+}
+
+// UserRules returns the provided default lateness rules
+// with the user's settings applied.
+func UserRules(userStore store.Store, userID uint, defaults lateness.Rules) (lateness.Rules, error) {
+	data, err := userStore.Load(userID)
+	if err != nil {
+		return lateness.Rules{}, err
+	}
+
+	return data.Settings.Apply(defaults), nil
+}
@@ -0,0 +1,170 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/tokens"
+)
+
+type Handler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger     lager.Logger
+	tokenStore tokens.Store
+}
+
+func NewHandler(
+	logger lager.Logger,
+	tokenStore tokens.Store,
+) Handler {
+	return &handler{
+		logger:     logger.Session("api-v1-tokens"),
+		tokenStore: tokenStore,
+	}
+}
+
+type createRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// createResponse is the only time a token's secret is returned.
+type createResponse struct {
+	tokens.Token
+	Secret string `json:"secret"`
+}
+
+// List returns the logged-in user's API tokens, without their secrets.
+func (h handler) List(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	_, userID, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	userTokens, err := h.tokenStore.List(userID)
+	if err != nil {
+		h.logger.Error("failed to list tokens", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, userTokens)
+}
+
+// Create creates an API token for the logged-in user, acting with
+// the session's Wunderlist access token.
+func (h handler) Create(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	var req createRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Info("invalid token request", lager.Data{"error": err.Error()})
+		http.Error(w, fmt.Sprintf("invalid token request: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var problems []string
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		problems = append(problems, "name must be provided")
+	}
+
+	scopes, err := tokens.ParseScopes(req.Scopes)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		err := fmt.Errorf("%s", strings.Join(problems, "; "))
+		h.logger.Info("invalid token request", lager.Data{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	accessToken, userID, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	token, secret, err := h.tokenStore.Create(userID, req.Name, scopes, accessToken)
+	if err != nil {
+		h.logger.Error("failed to create token", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	h.logger.Info("token-created", lager.Data{"user-id": userID, "token-id": token.ID, "scopes": scopes})
+	h.writeJSON(w, http.StatusCreated, createResponse{Token: token, Secret: secret})
+}
+
+// Revoke deletes one of the logged-in user's API tokens.
+func (h handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	id := mux.Vars(r)["id"]
+
+	_, userID, ok := h.user(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.tokenStore.Revoke(userID, id)
+	if err == tokens.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to revoke token", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	h.logger.Info("token-revoked", lager.Data{"user-id": userID, "token-id": id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// user returns the access token and ID of the user logged in to the
+// request's session. If they cannot be determined an error is written
+// to w and false returned.
+func (h handler) user(w http.ResponseWriter, r *http.Request) (string, uint, bool) {
+	accessToken, ok := middleware.AccessToken(r)
+	if !ok {
+		err := fmt.Errorf("accessToken not found in request")
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), 500)
+		return "", 0, false
+	}
+
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		err := fmt.Errorf("user not found in request")
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), 500)
+		return "", 0, false
+	}
+
+	return accessToken, userID, true
+}
+
+func (h handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		h.logger.Error("failed to serialize response", err)
+	}
+}
@@ -0,0 +1,39 @@
+package background
+
+import (
+	"context"
+	"sync"
+)
+
+// Jobs tracks work done outside of requests, such as registering
+// webhooks after login, so that it can finish before tardy exits.
+type Jobs struct {
+	wg sync.WaitGroup
+}
+
+// Go runs fn in a new goroutine.
+func (j *Jobs) Go(fn func()) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		fn()
+	}()
+}
+
+// Wait returns once every job has finished, or with ctx's error if it is
+// done first. No new jobs should be started once Wait has been called,
+// so the servers which start them should be shut down first.
+func (j *Jobs) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
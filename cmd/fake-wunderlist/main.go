@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/robdimsdale/tardy/tardytest"
+)
+
+func main() {
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "12346"
+	}
+
+	clientID := os.Getenv("CLIENT_ID")
+	clientSecret := os.Getenv("CLIENT_SECRET")
+
+	if clientID == "" || clientSecret == "" {
+		fmt.Printf("clientID and clientSecret must be provided and non-empty\n")
+		os.Exit(2)
+	}
+
+	days := 90
+	if d := os.Getenv("SAMPLE_DAYS"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil {
+			fmt.Printf("SAMPLE_DAYS must be an integer: %s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	fake := tardytest.NewFakeWunderlist(clientID, clientSecret)
+
+	err := fake.AddSampleData(1, days)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("port: %s\n", port)
+	fmt.Printf("Point tardy at this server with:\n")
+	fmt.Printf("  WUNDERLIST_AUTH_URL=http://localhost:%s\n", port)
+	fmt.Printf("  WUNDERLIST_API_URL=http://localhost:%s/api/v1\n", port)
+
+	err = http.ListenAndServe(fmt.Sprintf(":%s", port), fake)
+	panic(err)
+}
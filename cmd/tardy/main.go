@@ -1,133 +1,356 @@
 package main
 
 import (
-	"crypto/rand"
+	"crypto/tls"
+	"flag"
 	"fmt"
-	"math/big"
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/securecookie"
-	"github.com/gorilla/sessions"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/api/admin"
+	"github.com/robdimsdale/tardy/api/settings"
+	"github.com/robdimsdale/tardy/api/stats"
 	"github.com/robdimsdale/tardy/api/tasks"
+	apitokens "github.com/robdimsdale/tardy/api/tokens"
+	"github.com/robdimsdale/tardy/background"
+	"github.com/robdimsdale/tardy/config"
 	"github.com/robdimsdale/tardy/filesystem"
-	"github.com/robdimsdale/tardy/logger"
+	"github.com/robdimsdale/tardy/health"
+	tardylogger "github.com/robdimsdale/tardy/logger"
+	"github.com/robdimsdale/tardy/metrics"
 	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/session"
+	"github.com/robdimsdale/tardy/store"
+	"github.com/robdimsdale/tardy/tlscert"
+	"github.com/robdimsdale/tardy/tokens"
 	"github.com/robdimsdale/tardy/web/generated/static"
 	"github.com/robdimsdale/tardy/web/home"
 	"github.com/robdimsdale/tardy/web/login"
+	"github.com/robdimsdale/tardy/webhooks"
+	"github.com/robdimsdale/tardy/wunderlist"
 )
 
-var (
-	state string
-
-	accessToken string
-)
+// sessionPruneInterval is how often expired sessions are deleted
+// from the file session store.
+const sessionPruneInterval = time.Hour
 
 func main() {
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "12345"
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
 	}
 
-	redirectHost := os.Getenv("REDIRECT_HOST")
-	if redirectHost == "" {
-		redirectHost = fmt.Sprintf("http://localhost:%s", port)
+	c, err := config.Load("tardy", os.Args[1:], os.LookupEnv, os.Stderr)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(2)
 	}
 
-	clientID := os.Getenv("CLIENT_ID")
-	clientSecret := os.Getenv("CLIENT_SECRET")
+	// logFiles are closed once tardy has shut down.
+	var logFiles []io.Closer
 
-	if clientID == "" || clientSecret == "" {
-		fmt.Printf("clientID and clientSecret must be provided and non-empty\n")
-		os.Exit(2)
+	var logOutput io.Writer = os.Stdout
+	if c.LogFile != "" {
+		f, err := tardylogger.NewRotatingFile(c.LogFile, c.LogFileMaxSize, c.LogFileMaxBackups)
+		if err != nil {
+			fmt.Printf("Failed to open log file\n")
+			panic(err)
+		}
+		logOutput = f
+		logFiles = append(logFiles, f)
 	}
 
-	logger, _, err := logger.InitializeLogger(logger.LogLevel("debug"))
+	accessLogOutput := logOutput
+	if c.AccessLogFile != "" {
+		f, err := tardylogger.NewRotatingFile(c.AccessLogFile, c.LogFileMaxSize, c.LogFileMaxBackups)
+		if err != nil {
+			fmt.Printf("Failed to open access log file\n")
+			panic(err)
+		}
+		accessLogOutput = f
+		logFiles = append(logFiles, f)
+	}
+
+	redactor := tardylogger.NewRedactor(c.LogRedactKeys...).WithSecretPath(webhooks.SecretPath)
+	logger, sink, err := tardylogger.InitializeLogger(c.LogLevel, c.LogFormat, logOutput, redactor)
 	if err != nil {
 		fmt.Printf("Failed to initialize logger\n")
 		panic(err)
 	}
 
-	fmt.Printf("clientID: %s\n", clientID)
-	fmt.Printf("clientSecret: %s\n", clientID)
+	c.Print(os.Stdout)
 
-	fmt.Printf("port: %s\n", port)
+	tardylogger.HandleSignals(logger, sink)
 
-	state, err = createState()
+	handler, jobs, err := newHandler(c, logger, sink, redactor, accessLogOutput)
 	if err != nil {
-		panic(err)
+		logger.Fatal("exiting", err)
 	}
-	fmt.Printf("state: %s\n", state)
 
-	oauthRedirectURI := fmt.Sprintf("%s/login-resp", redirectHost)
+	if c.SessionStore == session.KindFile {
+		session.WatchExpired(logger, filepath.Join(c.DataDir, "sessions"), c.SessionMaxAge, sessionPruneInterval)
+	}
 
-	cookieHandler := securecookie.New(
-		securecookie.GenerateRandomKey(64),
-		securecookie.GenerateRandomKey(32),
-	)
+	listeners := []listener{
+		{server: newServer(c, c.Port, handler)},
+	}
 
-	cookieStore := sessions.NewCookieStore(securecookie.GenerateRandomKey(64))
+	if c.TLSCertFile != "" {
+		certReloader, err := tlscert.NewReloader(logger, c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			logger.Fatal("exiting", err)
+		}
+		certReloader.HandleSignals()
+		if c.TLSReloadInterval > 0 {
+			certReloader.Watch(c.TLSReloadInterval)
+		}
+
+		// The plain HTTP listener serves the same handler, whose transport
+		// security middleware redirects requests to the HTTPS listener.
+		// HTTP/2 is enabled automatically, as TLSNextProto is not set.
+		tlsServer := newServer(c, c.TLSPort, handler)
+		tlsServer.TLSConfig = &tls.Config{
+			GetCertificate: certReloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+		listeners = append(listeners, listener{server: tlsServer, tls: true})
+	}
+
+	exitCode := serve(logger, listeners, jobs, c.ShutdownTimeout)
+
+	for _, f := range logFiles {
+		f.Close()
+	}
+
+	os.Exit(exitCode)
+}
+
+// newHandler builds the stores, handlers and middleware which serve
+// tardy's routes. Background jobs started by handlers are tracked by
+// the returned Jobs so that shutdown can wait for them.
+func newHandler(
+	c config.Config,
+	logger lager.Logger,
+	sink *lager.ReconfigurableSink,
+	redactor tardylogger.Redactor,
+	accessLogOutput io.Writer,
+) (http.Handler, *background.Jobs, error) {
+	oauthRedirectURI := fmt.Sprintf("%s/login-resp", c.RedirectHost)
+
+	sessionKeyPairs := c.SessionKeys
+	if len(sessionKeyPairs) == 0 {
+		logger.Info("session_keys not configured - users will need to log in again after restart")
+		sessionKeyPairs = []session.KeyPair{session.GenerateKeyPair()}
+	}
+
+	sessionStore, err := session.NewStore(
+		logger,
+		c.SessionStore,
+		filepath.Join(c.DataDir, "sessions"),
+		int(c.SessionMaxAge/time.Second),
+		c.SecureCookies(),
+		sessionKeyPairs,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	templates, err := filesystem.LoadTemplates()
 	if err != nil {
-		logger.Fatal("exiting", err)
+		return nil, nil, err
+	}
+
+	tokenStore, err := tokens.NewFileStore(
+		filepath.Join(c.DataDir, "tokens", "tokens.json"),
+		sessionKeyPairs,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	homeHandler := home.NewHandler(logger, templates, sessionStore)
+	taskStore, err := store.NewFileStore(c.DataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var webhookKey []byte
+	if c.WebhookSecret != "" {
+		webhookKey = []byte(c.WebhookSecret.Value())
+	} else {
+		logger.Info("webhook_secret not configured - webhooks will need re-registering after restart")
+		webhookKey = securecookie.GenerateRandomKey(32)
 	}
+	webhookSigner := webhooks.NewSigner(webhookKey)
+
+	metricsRegistry := metrics.NewRegistry()
+	wunderlistMetrics := wunderlist.NewMetrics(metricsRegistry)
+
+	if c.SessionStore == session.KindFile {
+		sessionDir := filepath.Join(c.DataDir, "sessions")
+		metricsRegistry.NewGaugeFunc("tardy_active_sessions", "Unexpired sessions held by the server.", nil, func() []metrics.Sample {
+			count, err := session.CountActive(sessionDir, c.SessionMaxAge)
+			if err != nil {
+				logger.Error("failed to count sessions", err)
+				return nil
+			}
+			return []metrics.Sample{{Value: float64(count)}}
+		})
+	}
+
+	if c.MetricsUserLateness {
+		store.RegisterLatenessMetrics(logger, metricsRegistry, taskStore, c.Lateness)
+	}
+
+	wunderlistTaskSourceFactory := wunderlist.NewTaskSourceFactory(c.ClientID, c.WunderlistAPIURL, wunderlistMetrics)
+	taskSourceFactory := store.NewCachingTaskSourceFactory(
+		taskStore,
+		wunderlistTaskSourceFactory,
+		metricsRegistry,
+	)
+
+	tasksHandler := tasks.NewHandler(logger, taskSourceFactory, taskStore, c.Lateness)
+	statsHandler := stats.NewHandler(logger, taskSourceFactory, taskStore, c.Lateness)
+	settingsHandler := settings.NewHandler(logger, taskStore, c.Lateness)
+	tokensHandler := apitokens.NewHandler(logger, tokenStore)
+	webhooksHandler := webhooks.NewHandler(logger, webhookSigner, taskStore)
+	adminHandler := admin.NewHandler(logger, sink, c.AdminUserIDs)
 
-	homeHandler := home.NewHandler(logger, templates)
-	tasksHandler := tasks.NewHandler(logger, clientID, cookieStore)
+	webhookRegistrar := wunderlist.NewWebhookRegistrar(
+		logger,
+		c.ClientID,
+		c.WunderlistAPIURL,
+		c.RedirectHost,
+		webhookSigner,
+		wunderlistMetrics,
+	)
 
-	cookieMaxAge := 3600
+	jobs := &background.Jobs{}
 	loginHandler := login.NewHandler(
 		logger,
-		cookieHandler,
-		cookieMaxAge,
-		cookieStore,
-		clientID,
-		clientSecret,
+		session.Codecs(sessionKeyPairs),
+		sessionStore,
+		c.ClientID,
+		c.ClientSecret.Value(),
+		c.WunderlistAuthURL,
 		oauthRedirectURI,
-		state,
+		c.LoginStateTTL,
+		c.SecureCookies(),
+		webhookRegistrar,
+		wunderlistTaskSourceFactory,
+		taskStore,
+		jobs,
 	)
 
+	healthHandler := health.NewHandler(logger, []health.NamedCheck{
+		{Name: "templates", Check: health.TemplatesLoaded(templates, "homepage", "error")},
+		{Name: "store", Check: health.DirWritable(c.DataDir)},
+		{Name: "wunderlist", Check: health.Cached(
+			health.URLReachable(c.WunderlistAPIURL, 5*time.Second),
+			c.ReadinessCheckTTL,
+		)},
+	})
+
 	staticFileServer := http.FileServer(static.FS(false))
 
+	// Routes are named by their path templates, which label their metrics.
+	// The router keeps the matched route in gorilla/context for the metrics
+	// middleware, which is cleared by context.ClearHandler below instead.
 	rtr := mux.NewRouter()
+	rtr.KeepContext = true
+
+	rtr.PathPrefix("/static/").Handler(staticFileServer).Name("/static/")
+
+	rtr.HandleFunc("/", homeHandler.Home).Methods("GET").Name("/")
 
-	rtr.PathPrefix("/static/").Handler(staticFileServer)
+	rtr.HandleFunc("/login", loginHandler.LoginGET).Methods("GET").Name("/login")
+	rtr.HandleFunc("/login-resp", loginHandler.LoginResponse).Methods("GET").Name("/login-resp")
+	rtr.HandleFunc("/logout", loginHandler.LogoutPOST).Methods("POST").Name("/logout")
 
-	rtr.HandleFunc("/", homeHandler.Home).Methods("GET")
+	rtr.HandleFunc("/healthz", healthHandler.Healthz).Methods("GET").Name("/healthz")
+	rtr.HandleFunc("/readyz", healthHandler.Readyz).Methods("GET").Name("/readyz")
 
-	rtr.HandleFunc("/login", loginHandler.LoginGET).Methods("GET")
-	rtr.HandleFunc("/login-resp", loginHandler.LoginResponse).Methods("GET")
-	rtr.HandleFunc("/logout", loginHandler.LogoutPOST).Methods("POST")
+	webhookRoute := webhooks.PathPrefix + "/{userID}/{signature}"
+	rtr.HandleFunc(webhookRoute, webhooksHandler.Receive).Methods("POST").Name(webhookRoute)
+
+	if c.MetricsToken != "" {
+		rtr.Handle("/metrics", metrics.NewHandler(logger, metricsRegistry, c.MetricsToken.Value())).Methods("GET").Name("/metrics")
+	}
 
 	a := rtr.PathPrefix("/api/v1").Subrouter()
-	a.HandleFunc("/tasks", tasksHandler.Tasks).Methods("GET")
+	a.HandleFunc("/tasks", tasksHandler.Tasks).Methods("GET").Name("/api/v1/tasks")
+	a.HandleFunc("/tasks/overdue", tasksHandler.Overdue).Methods("GET").Name("/api/v1/tasks/overdue")
+	a.HandleFunc("/tasks/export", tasksHandler.Export).Methods("GET").Name("/api/v1/tasks/export")
+	a.HandleFunc("/stats", statsHandler.Stats).Methods("GET").Name("/api/v1/stats")
+	a.HandleFunc("/settings", settingsHandler.Get).Methods("GET").Name("/api/v1/settings")
+	a.HandleFunc("/settings", settingsHandler.Put).Methods("PUT").Name("/api/v1/settings")
+	a.HandleFunc("/tokens", tokensHandler.List).Methods("GET").Name("/api/v1/tokens")
+	a.HandleFunc("/tokens", tokensHandler.Create).Methods("POST").Name("/api/v1/tokens")
+	a.HandleFunc("/tokens/{id}", tokensHandler.Revoke).Methods("DELETE").Name("/api/v1/tokens/{id}")
+	a.HandleFunc("/admin/log-level", adminHandler.GetLogLevel).Methods("GET").Name("/api/v1/admin/log-level")
+	a.HandleFunc("/admin/log-level", adminHandler.PutLogLevel).Methods("PUT").Name("/api/v1/admin/log-level")
+
+	// Without a trusted proxy or a TLS listener no request is seen as
+	// HTTPS, so every request, except from localhost, is redirected.
+	if c.TransportSecurity.RedirectToHTTPS && c.TLSCertFile == "" && c.TransportSecurity.TrustedProxies.Empty() {
+		logger.Error("force_https is set without trusted_proxies or tls_cert_file - requests will be redirected to https indefinitely", nil)
+	}
+
+	// Load balancers probe over plain HTTP and do not follow redirects.
+	c.TransportSecurity.ExemptPaths = []string{"/healthz", "/readyz"}
 
 	m := middleware.Chain{
-		middleware.NewPanicRecovery(logger),
-		middleware.NewLogger(logger),
-		middleware.NewHTTPSEnforcer(logger),
-		middleware.NewAuth(logger, cookieHandler, cookieStore),
+		middleware.NewRequestID(logger),
+		middleware.NewMetrics(metricsRegistry),
+		middleware.NewAccessLog(logger, accessLogOutput, redactor, c.AccessLog),
+		middleware.NewPanicRecovery(logger, redactor, templates, nil),
+		middleware.NewTransportSecurity(logger, c.TransportSecurity),
+		middleware.NewAuth(logger, sessionStore, tokenStore),
+		middleware.NewCSRF(logger, sessionStore),
 	}
 
-	handler := m.Wrap(rtr)
+	// Sessions are cached per request in gorilla/context,
+	// which must be cleared once the request is complete.
+	return context.ClearHandler(m.Wrap(rtr)), jobs, nil
+}
 
-	err = http.ListenAndServe(fmt.Sprintf(":%s", port), handler)
-	panic(err)
+func newServer(c config.Config, port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
 }
 
-func createState() (string, error) {
-	stateBytes := make([]byte, 64)
-	for i := range stateBytes {
-		val, err := rand.Int(rand.Reader, big.NewInt('~'-'!'))
-		if err != nil {
-			return "", err
-		}
-		stateBytes[i] = byte(val.Int64()) + '!'
+// configCommand runs the "tardy config" subcommands, returning the exit code.
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintf(os.Stderr, "usage: tardy config check [flags]\n")
+		return 2
+	}
+
+	c, err := config.Load("tardy config check", args[1:], os.LookupEnv, os.Stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
 	}
 
-	return string(stateBytes), nil
+	c.Print(os.Stdout)
+	fmt.Printf("configuration is valid\n")
+	return 0
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/background"
+	"github.com/robdimsdale/tardy/config"
+	tardylogger "github.com/robdimsdale/tardy/logger"
+	"github.com/robdimsdale/tardy/tardytest"
+	"github.com/robdimsdale/tardy/webhooks"
+	"github.com/robdimsdale/wl"
+)
+
+// TestLoginAndFetchTasks drives a browser's login through the fake
+// Wunderlist and then fetches the chart's tasks, entirely offline.
+func TestLoginAndFetchTasks(t *testing.T) {
+	fake := tardytest.NewServer("client-id", "client-secret")
+	defer fake.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	list := fake.Fake.AddList("Chores")
+	for _, task := range []wl.Task{
+		{ListID: list.ID, Title: "late", DueDate: today.AddDate(0, 0, -5), Completed: true, CompletedAt: today.AddDate(0, 0, -2)},
+		{ListID: list.ID, Title: "early", DueDate: today.AddDate(0, 0, -3), Completed: true, CompletedAt: today.AddDate(0, 0, -4)},
+		{ListID: list.ID, Title: "open", DueDate: today.AddDate(0, 0, 3)},
+	} {
+		_, err := fake.Fake.AddTask(task)
+		if err != nil {
+			t.Fatalf("failed to add task: %s", err)
+		}
+	}
+
+	dataDir, err := ioutil.TempDir("", "tardy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	// The redirect host must be known before the handler is built,
+	// so the server is started first and given the handler after.
+	var handler http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	env := map[string]string{
+		"CLIENT_ID":           "client-id",
+		"CLIENT_SECRET":       "client-secret",
+		"WUNDERLIST_AUTH_URL": fake.AuthURL(),
+		"WUNDERLIST_API_URL":  fake.APIURL(),
+		"REDIRECT_HOST":       srv.URL,
+		"DATA_DIR":            dataDir,
+		"FORCE_HTTPS":         "false",
+		"LOG_LEVEL":           "fatal",
+	}
+	c, err := config.Load("tardy", nil, func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to load config: %s", err)
+	}
+
+	redactor := tardylogger.NewRedactor().WithSecretPath(webhooks.SecretPath)
+	logger, sink, err := tardylogger.InitializeLogger(c.LogLevel, c.LogFormat, ioutil.Discard, redactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs *background.Jobs
+	handler, jobs, err = newHandler(c, logger, sink, redactor, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to build handler: %s", err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(srv.URL + "/api/v1/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before login, got %d", resp.StatusCode)
+	}
+
+	// The client follows the redirects to the fake's authorize endpoint,
+	// back to the callback and on to the home page.
+	resp, err = client.Get(srv.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/" {
+		t.Fatalf("expected login to end on the home page, got %d at %s", resp.StatusCode, resp.Request.URL)
+	}
+
+	resp, err = client.Get(srv.URL + "/api/v1/tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 fetching tasks, got %d", resp.StatusCode)
+	}
+
+	var tasks []tardy.Task
+	err = json.NewDecoder(resp.Body).Decode(&tasks)
+	if err != nil {
+		t.Fatalf("failed to decode tasks: %s", err)
+	}
+
+	byTitle := map[string]tardy.Task{}
+	for _, task := range tasks {
+		byTitle[task.Title] = task
+	}
+	if len(tasks) != 2 || byTitle["late"].Title == "" || byTitle["early"].Title == "" {
+		t.Fatalf("expected the two completed tasks, got %+v", tasks)
+	}
+	if byTitle["late"].ListTitle != "Chores" {
+		t.Errorf("expected tasks to be labelled with their list, got %q", byTitle["late"].ListTitle)
+	}
+	if byTitle["late"].Days <= 0 {
+		t.Errorf("expected the late task to be late, got %d days", byTitle["late"].Days)
+	}
+	if byTitle["early"].Days >= 0 {
+		t.Errorf("expected the early task to be early, got %d days", byTitle["early"].Days)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = jobs.Wait(ctx)
+	if err != nil {
+		t.Fatalf("background jobs did not finish: %s", err)
+	}
+	if len(fake.Fake.Webhooks()) != 1 {
+		t.Errorf("expected a webhook to be registered for the list, got %d", len(fake.Fake.Webhooks()))
+	}
+}
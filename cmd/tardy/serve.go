@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/background"
+)
+
+// listener is a server and whether it serves HTTPS.
+type listener struct {
+	server *http.Server
+	tls    bool
+}
+
+// serve runs the listeners until one fails or SIGTERM or SIGINT is
+// received. The listeners are then shut down, waiting up to
+// shutdownTimeout for in-flight requests and then background jobs to
+// finish. It returns the process's exit code.
+func serve(logger lager.Logger, listeners []listener, jobs *background.Jobs, shutdownTimeout time.Duration) int {
+	logger = logger.Session("serve")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l listener) {
+			logger.Info("listening", lager.Data{"addr": l.server.Addr, "tls": l.tls})
+
+			var err error
+			if l.tls {
+				err = l.server.ListenAndServeTLS("", "")
+			} else {
+				err = l.server.ListenAndServe()
+			}
+			errs <- err
+		}(l)
+	}
+
+	exitCode := 0
+	select {
+	case s := <-signals:
+		logger.Info("shutting-down", lager.Data{"signal": s.String()})
+	case err := <-errs:
+		logger.Error("listener-failed", err)
+		exitCode = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, l := range listeners {
+		err := l.server.Shutdown(ctx)
+		if err != nil {
+			logger.Error("failed-to-drain-requests", err, lager.Data{"addr": l.server.Addr})
+			exitCode = 1
+		}
+	}
+
+	err := jobs.Wait(ctx)
+	if err != nil {
+		logger.Error("failed-to-finish-background-jobs", err)
+		exitCode = 1
+	}
+
+	logger.Info("shut-down")
+	return exitCode
+}
@@ -0,0 +1,509 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/logger"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/session"
+)
+
+// Secret is a configuration value which must not be printed or logged.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+// String redacts the secret, so that it is hidden when formatted with %v.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(s.String())), nil
+}
+
+// Value returns the secret itself.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// Config is tardy's validated configuration.
+type Config struct {
+	Port         int
+	RedirectHost string
+
+	ClientID     string
+	ClientSecret Secret
+
+	WunderlistAuthURL string
+	WunderlistAPIURL  string
+
+	// WebhookSecret is empty if a random key should be used.
+	WebhookSecret Secret
+
+	DataDir string
+
+	LogLevel  logger.LogLevel
+	LogFormat logger.Format
+	// LogFile is empty if logs should be written to stdout.
+	LogFile           string
+	LogFileMaxSize    int64
+	LogFileMaxBackups int
+	// LogRedactKeys are redacted in addition to logger.DefaultRedactedKeys.
+	LogRedactKeys []string
+
+	// AccessLogFile is empty if the access log should be
+	// written to the same output as other logs.
+	AccessLogFile string
+	AccessLog     middleware.AccessLogConfig
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+
+	// TLSCertFile and TLSKeyFile are empty if tardy only serves plain HTTP.
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSPort           int
+	TLSReloadInterval time.Duration
+
+	TransportSecurity middleware.TransportSecurityConfig
+
+	// MetricsToken is empty if /metrics is disabled.
+	MetricsToken        Secret
+	MetricsUserLateness bool
+
+	ReadinessCheckTTL time.Duration
+
+	AdminUserIDs []uint
+
+	SessionStore string
+	// SessionKeys is empty if random keys should be used.
+	SessionKeys   []session.KeyPair
+	SessionMaxAge time.Duration
+	LoginStateTTL time.Duration
+
+	Lateness lateness.Rules
+
+	// values and sources record the raw value of every setting
+	// and where it came from, for printing.
+	values  map[string]string
+	sources map[string]string
+}
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Load builds the configuration from, in decreasing order of precedence,
+// command-line flags, environment variables, the config file and defaults.
+// The config file is named by the -config flag or the TARDY_CONFIG
+// environment variable and may be JSON (.json) or a flat YAML mapping
+// (.yml or .yaml). getenv is usually os.LookupEnv.
+func Load(name string, args []string, getenv func(string) (string, bool), output io.Writer) (Config, error) {
+	values := map[string]string{}
+	sources := map[string]string{}
+	for _, s := range settings {
+		if s.def != "" {
+			values[s.key] = s.def
+			sources[s.key] = "default"
+		}
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	configFile := fs.String(fileFlag, "", "path to a JSON or YAML config file (env "+fileEnv+")")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		flagValues[s.key] = fs.String(flagName(s.key), "", usage)
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *configFile == "" {
+		*configFile, _ = getenv(fileEnv)
+	}
+
+	if *configFile != "" {
+		fileValues, err := loadFile(*configFile)
+		if err != nil {
+			return Config{}, err
+		}
+
+		var unknown []string
+		for key, value := range fileValues {
+			if _, ok := lookup(key); !ok {
+				unknown = append(unknown, key)
+				continue
+			}
+			values[key] = value
+			sources[key] = "file " + *configFile
+		}
+
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return Config{}, fmt.Errorf("unknown settings in %s: %s", *configFile, strings.Join(unknown, ", "))
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := getenv(s.env); ok {
+			values[s.key] = value
+			sources[s.key] = "env " + s.env
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == fileFlag {
+			return
+		}
+		key := strings.Replace(f.Name, "-", "_", -1)
+		values[key] = *flagValues[key]
+		sources[key] = "flag -" + f.Name
+	})
+
+	return parse(values, sources)
+}
+
+func flagName(key string) string {
+	return strings.Replace(key, "_", "-", -1)
+}
+
+func lookup(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// parse converts the raw values into a Config,
+// collecting every problem rather than stopping at the first.
+func parse(values map[string]string, sources map[string]string) (Config, error) {
+	var errs ValidationError
+	problem := func(key string, format string, args ...interface{}) {
+		source := sources[key]
+		if source == "" {
+			source = "not set"
+		}
+		errs = append(errs, fmt.Sprintf("%s (%s): %s", key, source, fmt.Sprintf(format, args...)))
+	}
+
+	c := Config{
+		ClientID:          values["client_id"],
+		ClientSecret:      Secret(values["client_secret"]),
+		WunderlistAuthURL: strings.TrimRight(values["wunderlist_auth_url"], "/"),
+		WunderlistAPIURL:  strings.TrimRight(values["wunderlist_api_url"], "/"),
+		WebhookSecret:     Secret(values["webhook_secret"]),
+		DataDir:           values["data_dir"],
+		LogLevel:          logger.LogLevel(values["log_level"]),
+		LogFormat:         logger.Format(values["log_format"]),
+		LogFile:           values["log_file"],
+		SessionStore:      values["session_store"],
+		MetricsToken:      Secret(values["metrics_token"]),
+		values:            values,
+		sources:           sources,
+	}
+
+	port, err := strconv.Atoi(values["port"])
+	if err != nil || port < 1 || port > 65535 {
+		problem("port", "must be a port number, got %q", values["port"])
+	}
+	c.Port = port
+
+	c.RedirectHost = strings.TrimRight(values["redirect_host"], "/")
+	if c.RedirectHost == "" {
+		c.RedirectHost = fmt.Sprintf("http://localhost:%d", port)
+		values["redirect_host"], sources["redirect_host"] = c.RedirectHost, "default"
+	}
+
+	for _, u := range []struct{ key, value string }{
+		{"redirect_host", c.RedirectHost},
+		{"wunderlist_auth_url", c.WunderlistAuthURL},
+		{"wunderlist_api_url", c.WunderlistAPIURL},
+	} {
+		parsed, err := url.Parse(u.value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problem(u.key, "must be an http or https URL, got %q", u.value)
+		}
+	}
+
+	if c.ClientID == "" {
+		problem("client_id", "must be provided")
+	}
+	if c.ClientSecret == "" {
+		problem("client_secret", "must be provided")
+	}
+
+	if c.DataDir == "" {
+		c.DataDir = filepath.Join(os.TempDir(), "tardy")
+		values["data_dir"], sources["data_dir"] = c.DataDir, "default"
+	}
+
+	switch c.LogLevel {
+	case logger.LogLevelDebug, logger.LogLevelInfo, logger.LogLevelError, logger.LogLevelFatal:
+	default:
+		problem("log_level", "must be debug, info, error or fatal, got %q", c.LogLevel)
+	}
+
+	switch c.LogFormat {
+	case logger.FormatJSON, logger.FormatHuman:
+	default:
+		problem("log_format", "must be %s or %s, got %q", logger.FormatJSON, logger.FormatHuman, c.LogFormat)
+	}
+
+	maxSize, err := strconv.ParseInt(values["log_file_max_size"], 10, 64)
+	if err != nil || maxSize < 1 {
+		problem("log_file_max_size", "must be a positive number of megabytes, got %q", values["log_file_max_size"])
+	}
+	c.LogFileMaxSize = maxSize * 1024 * 1024
+
+	c.LogFileMaxBackups, err = strconv.Atoi(values["log_file_max_backups"])
+	if err != nil || c.LogFileMaxBackups < 0 {
+		problem("log_file_max_backups", "must be a non-negative number, got %q", values["log_file_max_backups"])
+	}
+
+	c.AccessLogFile = values["access_log_file"]
+	c.AccessLog.Format = middleware.AccessLogFormat(values["access_log_format"])
+	switch c.AccessLog.Format {
+	case middleware.AccessLogCommon, middleware.AccessLogCombined, middleware.AccessLogJSON:
+	default:
+		problem("access_log_format", "must be %s, %s or %s, got %q", middleware.AccessLogCommon, middleware.AccessLogCombined, middleware.AccessLogJSON, c.AccessLog.Format)
+	}
+
+	c.AccessLog.SampleRate, err = strconv.ParseFloat(values["access_log_sample_rate"], 64)
+	if err != nil || c.AccessLog.SampleRate < 0 || c.AccessLog.SampleRate > 1 {
+		problem("access_log_sample_rate", "must be a number from 0 to 1, got %q", values["access_log_sample_rate"])
+	}
+
+	c.AccessLog.BodyLimit, err = strconv.Atoi(values["access_log_body_limit"])
+	if err != nil || c.AccessLog.BodyLimit < 0 {
+		problem("access_log_body_limit", "must be a non-negative number of bytes, got %q", values["access_log_body_limit"])
+	}
+
+	if keys := values["log_redact_keys"]; keys != "" {
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				c.LogRedactKeys = append(c.LogRedactKeys, key)
+			}
+		}
+	}
+
+	c.ReadHeaderTimeout = parsePositiveDuration(values, "read_header_timeout", problem)
+	c.ReadTimeout = parsePositiveDuration(values, "read_timeout", problem)
+	c.WriteTimeout = parsePositiveDuration(values, "write_timeout", problem)
+	c.IdleTimeout = parsePositiveDuration(values, "idle_timeout", problem)
+	c.ShutdownTimeout = parsePositiveDuration(values, "shutdown_timeout", problem)
+	c.ReadinessCheckTTL = parsePositiveDuration(values, "readiness_check_ttl", problem)
+
+	c.TLSCertFile = values["tls_cert_file"]
+	c.TLSKeyFile = values["tls_key_file"]
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		problem("tls_key_file", "tls_cert_file and tls_key_file must be provided together")
+	}
+
+	c.TLSPort, err = strconv.Atoi(values["tls_port"])
+	if err != nil || c.TLSPort < 1 || c.TLSPort > 65535 {
+		problem("tls_port", "must be a port number, got %q", values["tls_port"])
+	} else if c.TLSCertFile != "" && c.TLSPort == c.Port {
+		problem("tls_port", "must differ from port, which redirects to it")
+	}
+
+	c.TLSReloadInterval, err = time.ParseDuration(values["tls_reload_interval"])
+	if err != nil || c.TLSReloadInterval < 0 {
+		problem("tls_reload_interval", "must be a non-negative duration, got %q", values["tls_reload_interval"])
+	}
+
+	c.TransportSecurity = parseTransportSecurity(values, problem)
+	if c.TLSCertFile != "" {
+		c.TransportSecurity.HTTPSPort = c.TLSPort
+	}
+
+	c.MetricsUserLateness = parseBool(values, "metrics_user_lateness", problem)
+
+	if ids := values["admin_user_ids"]; ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			userID, err := strconv.ParseUint(strings.TrimSpace(id), 10, 0)
+			if err != nil {
+				problem("admin_user_ids", "must be comma-separated user IDs, got %q", ids)
+				break
+			}
+			c.AdminUserIDs = append(c.AdminUserIDs, uint(userID))
+		}
+	}
+
+	switch c.SessionStore {
+	case session.KindFile, session.KindCookie:
+	default:
+		problem("session_store", "must be %s or %s, got %q", session.KindFile, session.KindCookie, c.SessionStore)
+	}
+
+	if keys := values["session_keys"]; keys != "" {
+		c.SessionKeys, err = session.ParseKeys(keys)
+		if err != nil {
+			problem("session_keys", "%s", err.Error())
+		}
+	}
+
+	c.SessionMaxAge = parsePositiveDuration(values, "session_max_age", problem)
+	c.LoginStateTTL = parsePositiveDuration(values, "login_state_ttl", problem)
+
+	c.Lateness = parseLateness(values, problem)
+
+	// Individually valid lateness settings may still combine into
+	// invalid rules, e.g. business mode with hour resolution.
+	err = c.Lateness.Validate()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return Config{}, errs
+	}
+
+	return c, nil
+}
+
+func parsePositiveDuration(values map[string]string, key string, problem func(string, string, ...interface{})) time.Duration {
+	d, err := time.ParseDuration(values[key])
+	if err != nil || d <= 0 {
+		problem(key, "must be a positive duration, e.g. 1h30m, got %q", values[key])
+	}
+	return d
+}
+
+func parseBool(values map[string]string, key string, problem func(string, string, ...interface{})) bool {
+	b, err := strconv.ParseBool(values[key])
+	if err != nil {
+		problem(key, "must be true or false, got %q", values[key])
+	}
+	return b
+}
+
+func parseTransportSecurity(values map[string]string, problem func(string, string, ...interface{})) middleware.TransportSecurityConfig {
+	ts := middleware.TransportSecurityConfig{
+		RedirectToHTTPS:       parseBool(values, "force_https", problem),
+		ExemptLocalhost:       parseBool(values, "force_https_exempt_localhost", problem),
+		HSTSIncludeSubdomains: parseBool(values, "hsts_include_subdomains", problem),
+		HSTSPreload:           parseBool(values, "hsts_preload", problem),
+	}
+
+	proxies, err := middleware.ParseTrustedProxies(values["trusted_proxies"])
+	if err != nil {
+		problem("trusted_proxies", "%s", err.Error())
+	}
+	ts.TrustedProxies = proxies
+
+	ts.HSTSMaxAge, err = time.ParseDuration(values["hsts_max_age"])
+	if err != nil || ts.HSTSMaxAge < 0 {
+		problem("hsts_max_age", "must be a non-negative duration, got %q", values["hsts_max_age"])
+	}
+
+	// These are the requirements for inclusion in browsers' preload lists.
+	if ts.HSTSPreload && (!ts.HSTSIncludeSubdomains || ts.HSTSMaxAge < 365*24*time.Hour) {
+		problem("hsts_preload", "requires hsts_include_subdomains and an hsts_max_age of at least 8760h")
+	}
+
+	return ts
+}
+
+func parseLateness(values map[string]string, problem func(string, string, ...interface{})) lateness.Rules {
+	rules := lateness.DefaultRules()
+
+	location, err := time.LoadLocation(values["lateness_timezone"])
+	if err != nil {
+		problem("lateness_timezone", "must be a time zone name, got %q", values["lateness_timezone"])
+	} else {
+		rules.Location = location
+	}
+
+	deadline, err := lateness.ParseDeadline(values["lateness_deadline"])
+	if err != nil {
+		problem("lateness_deadline", "%s", err.Error())
+	} else {
+		rules.Deadline = deadline
+	}
+
+	gracePeriod, err := time.ParseDuration(values["lateness_grace_period"])
+	if err != nil || gracePeriod < 0 {
+		problem("lateness_grace_period", "must be a non-negative duration, got %q", values["lateness_grace_period"])
+	} else {
+		rules.GracePeriod = gracePeriod
+	}
+
+	rules.Resolution = lateness.Resolution(values["lateness_resolution"])
+	rules.Mode = lateness.Mode(values["lateness_mode"])
+
+	weekend, err := lateness.ParseWeekend(values["lateness_weekend"])
+	if err != nil {
+		problem("lateness_weekend", "%s", err.Error())
+	} else {
+		rules.Weekend = weekend
+	}
+
+	if f := values["lateness_holidays_file"]; f != "" {
+		holidays, err := lateness.LoadCalendar(f)
+		if err != nil {
+			problem("lateness_holidays_file", "%s", err.Error())
+		} else {
+			rules.Holidays = holidays
+		}
+	}
+
+	return rules
+}
+
+// SecureCookies reports whether cookies should only be sent over HTTPS,
+// which is the case if tardy serves HTTPS itself or redirects to it.
+func (c Config) SecureCookies() bool {
+	return c.TLSCertFile != "" || c.TransportSecurity.RedirectToHTTPS
+}
+
+// Print writes every setting, its value and where the value came from.
+// Secrets are redacted.
+func (c Config) Print(w io.Writer) {
+	for _, s := range settings {
+		value := c.values[s.key]
+		if s.secret && value != "" {
+			value = redacted
+		}
+
+		source := c.sources[s.key]
+		if source == "" {
+			source = "not set"
+		}
+
+		fmt.Fprintf(w, "%-24s %-40s (%s)\n", s.key+":", value, source)
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loadFile reads the settings in a JSON or YAML config file.
+func loadFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSON(b)
+	case ".yml", ".yaml":
+		return parseYAML(b)
+	default:
+		return nil, fmt.Errorf("config file must be .json, .yml or .yaml, got %s", path)
+	}
+}
+
+// parseJSON reads a JSON object whose values are strings, numbers or booleans.
+func parseJSON(b []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err := d.Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON config file: %s", err.Error())
+	}
+
+	values := map[string]string{}
+	for key, v := range raw {
+		switch v := v.(type) {
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("invalid JSON config file: %s must be a string, number or boolean", key)
+		}
+	}
+
+	return values, nil
+}
+
+// parseYAML reads a flat YAML mapping of keys to scalar values,
+// which is all tardy's configuration needs. Nested mappings, lists
+// and multi-line values are not supported.
+func parseYAML(b []byte) (map[string]string, error) {
+	values := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		if line != trimmed {
+			return nil, fmt.Errorf("invalid YAML config file: line %d: nested values are not supported", n)
+		}
+
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid YAML config file: line %d: expected key: value", n)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value, err := yamlScalar(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML config file: line %d: %s", n, err.Error())
+		}
+
+		values[key] = value
+	}
+
+	return values, scanner.Err()
+}
+
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := closingQuote(s, '"')
+		if end < 0 || !isComment(s[end+1:]) {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := closingQuote(s, '\'')
+		if end < 0 || !isComment(s[end+1:]) {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return strings.Replace(s[1:end], "''", "'", -1), nil
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		return "", fmt.Errorf("only scalar values are supported")
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "~" || s == "null" {
+		return "", nil
+	}
+	return s, nil
+}
+
+// closingQuote returns the index of the quote ending the value quoted
+// by s[0], or -1 if there is none. Within double quotes a backslash
+// escapes the next character; within single quotes a quote is escaped
+// by doubling it.
+func closingQuote(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] != quote:
+		case quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
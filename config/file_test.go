@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	yaml := `---
+# tardy configuration
+port: 8080
+log_level: debug   # noisy
+client_secret: "s3cr#t \"quoted\" # not a comment"  # a comment
+webhook_secret: 'it''s # here'
+redirect_host: https://example.com/#fragment
+data_dir: ~
+metrics_token: null
+session_keys: ""
+`
+
+	values, err := parseYAML([]byte(yaml))
+	if err != nil {
+		t.Fatalf("failed to parse YAML: %s", err)
+	}
+
+	expected := map[string]string{
+		"port":           "8080",
+		"log_level":      "debug",
+		"client_secret":  `s3cr#t "quoted" # not a comment`,
+		"webhook_secret": "it's # here",
+		"redirect_host":  "https://example.com/#fragment",
+		"data_dir":       "",
+		"metrics_token":  "",
+		"session_keys":   "",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestParseYAMLRejectsUnsupportedValues(t *testing.T) {
+	for _, yaml := range []string{
+		"port 8080\n",
+		": 8080\n",
+		"admin_user_ids:\n  - 1\n",
+		"admin_user_ids: [1, 2]\n",
+		"access_log: {format: json}\n",
+		"client_secret: |\n",
+		"client_secret: >\n",
+		`client_secret: "unterminated` + "\n",
+		`client_secret: "escaped end\"` + "\n",
+		`client_secret: "value" trailing` + "\n",
+		"client_secret: 'it''s\n",
+		`client_secret: "bad \q escape"` + "\n",
+	} {
+		_, err := parseYAML([]byte(yaml))
+		if err == nil {
+			t.Errorf("expected an error parsing %q", yaml)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	values, err := parseJSON([]byte(`{"port": 8080, "force_https": false, "client_id": "id", "lateness_grace_period": "1h30m"}`))
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %s", err)
+	}
+
+	expected := map[string]string{
+		"port":                  "8080",
+		"force_https":           "false",
+		"client_id":             "id",
+		"lateness_grace_period": "1h30m",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	for _, json := range []string{`{"port": [8080]}`, `{"port": null}`, `["port"]`} {
+		_, err := parseJSON([]byte(json))
+		if err == nil {
+			t.Errorf("expected an error parsing %s", json)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tardy-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"tardy.yml":  "port: 8080\n",
+		"tardy.YAML": "port: 8080\n",
+		"tardy.json": `{"port": 8080}`,
+	} {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values, err := loadFile(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %s", name, err)
+		}
+		if values["port"] != "8080" {
+			t.Errorf("expected %s to set port, got %v", name, values)
+		}
+	}
+
+	path := filepath.Join(dir, "tardy.toml")
+	err = ioutil.WriteFile(path, []byte("port = 8080\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadFile(path)
+	if err == nil {
+		t.Errorf("expected an error loading a file of an unknown format")
+	}
+}
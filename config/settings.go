@@ -0,0 +1,72 @@
+package config
+
+import "github.com/robdimsdale/wl"
+
+// setting describes one configuration value and where it may be provided.
+// Every setting can be given as a flag, an environment variable or a key
+// in the config file; the flag name is the key with dashes for underscores.
+type setting struct {
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret bool
+}
+
+var settings = []setting{
+	{key: "port", env: "PORT", def: "12345", usage: "port to listen on"},
+	{key: "redirect_host", env: "REDIRECT_HOST", usage: "externally visible base URL of tardy, used in OAuth and webhook callbacks (default http://localhost:<port>)"},
+	{key: "client_id", env: "CLIENT_ID", usage: "Wunderlist OAuth client ID (required)"},
+	{key: "client_secret", env: "CLIENT_SECRET", usage: "Wunderlist OAuth client secret (required)", secret: true},
+	{key: "wunderlist_auth_url", env: "WUNDERLIST_AUTH_URL", def: "https://www.wunderlist.com", usage: "base URL of the Wunderlist OAuth endpoints"},
+	{key: "wunderlist_api_url", env: "WUNDERLIST_API_URL", def: wl.APIURL, usage: "base URL of the Wunderlist API"},
+	{key: "webhook_secret", env: "WEBHOOK_SECRET", usage: "key used to sign webhook URLs (default random, requiring webhooks to be re-registered after restart)", secret: true},
+	{key: "data_dir", env: "DATA_DIR", usage: "directory for cached tasks, sessions and API tokens (default <temp dir>/tardy)"},
+	{key: "log_level", env: "LOG_LEVEL", def: "info", usage: "minimum log level at startup: debug, info, error or fatal"},
+	{key: "log_format", env: "LOG_FORMAT", def: "json", usage: "log output format: json or human"},
+	{key: "log_file", env: "LOG_FILE", usage: "file to write logs to (default stdout)"},
+	{key: "log_file_max_size", env: "LOG_FILE_MAX_SIZE", def: "100", usage: "size in megabytes at which the log file is rotated"},
+	{key: "log_file_max_backups", env: "LOG_FILE_MAX_BACKUPS", def: "3", usage: "number of rotated log files to keep"},
+	{key: "log_redact_keys", env: "LOG_REDACT_KEYS", usage: "comma-separated log data, header and query keys to redact in addition to tokens, codes, cookies and secrets"},
+	{key: "access_log_format", env: "ACCESS_LOG_FORMAT", def: "json", usage: "access log format: common, combined or json"},
+	{key: "access_log_file", env: "ACCESS_LOG_FILE", usage: "file to write the access log to, rotated like log_file (default the same output as other logs)"},
+	{key: "access_log_sample_rate", env: "ACCESS_LOG_SAMPLE_RATE", def: "1", usage: "fraction of requests, 0 to 1, written to the access log; server errors are always written"},
+	{key: "access_log_body_limit", env: "ACCESS_LOG_BODY_LIMIT", def: "0", usage: "bytes of JSON and form bodies recorded in the json access log, with credentials redacted (default 0, not recorded)"},
+	{key: "read_header_timeout", env: "READ_HEADER_TIMEOUT", def: "10s", usage: "how long clients have to send request headers"},
+	{key: "read_timeout", env: "READ_TIMEOUT", def: "30s", usage: "how long clients have to send an entire request"},
+	{key: "write_timeout", env: "WRITE_TIMEOUT", def: "2m", usage: "how long a response may take, from the end of the request headers"},
+	{key: "idle_timeout", env: "IDLE_TIMEOUT", def: "2m", usage: "how long idle keep-alive connections are kept open"},
+	{key: "shutdown_timeout", env: "SHUTDOWN_TIMEOUT", def: "30s", usage: "how long in-flight requests and background jobs have to finish after SIGTERM"},
+	{key: "tls_cert_file", env: "TLS_CERT_FILE", usage: "PEM certificate chain for serving HTTPS directly (default none, serving only plain HTTP)"},
+	{key: "tls_key_file", env: "TLS_KEY_FILE", usage: "PEM private key for tls_cert_file"},
+	{key: "tls_port", env: "TLS_PORT", def: "443", usage: "port to serve HTTPS on when tls_cert_file is set; port then redirects to it"},
+	{key: "tls_reload_interval", env: "TLS_RELOAD_INTERVAL", def: "1m", usage: "how often to check the certificate files for changes; 0s only reloads on SIGHUP"},
+	{key: "force_https", env: "FORCE_HTTPS", def: "true", usage: "whether plain HTTP requests are redirected to HTTPS"},
+	{key: "force_https_exempt_localhost", env: "FORCE_HTTPS_EXEMPT_LOCALHOST", def: "true", usage: "whether plain HTTP requests for localhost are served, for local development"},
+	{key: "trusted_proxies", env: "TRUSTED_PROXIES", usage: "comma-separated IPs and CIDR ranges of proxies trusted to set Forwarded and X-Forwarded-Proto; * trusts all, for platforms like Heroku whose router is the only way in (default none)"},
+	{key: "hsts_max_age", env: "HSTS_MAX_AGE", def: "8760h", usage: "max-age of the Strict-Transport-Security header; 0s disables it"},
+	{key: "hsts_include_subdomains", env: "HSTS_INCLUDE_SUBDOMAINS", def: "false", usage: "whether HSTS also applies to subdomains"},
+	{key: "hsts_preload", env: "HSTS_PRELOAD", def: "false", usage: "whether to request inclusion in browsers' HSTS preload lists"},
+	{key: "metrics_token", env: "METRICS_TOKEN", usage: "bearer token required to scrape /metrics (default none, disabling /metrics)", secret: true},
+	{key: "metrics_user_lateness", env: "METRICS_USER_LATENESS", def: "false", usage: "whether /metrics includes per-user lateness gauges"},
+	{key: "readiness_check_ttl", env: "READINESS_CHECK_TTL", def: "30s", usage: "how long /readyz reuses the result of checking that the Wunderlist API is reachable"},
+	{key: "admin_user_ids", env: "ADMIN_USER_IDS", usage: "comma-separated Wunderlist user IDs allowed to use the admin API"},
+	{key: "session_store", env: "SESSION_STORE", def: "file", usage: "where session data is kept: file or cookie"},
+	{key: "session_keys", env: "SESSION_KEYS", usage: "comma-separated base64 <hash key>:<block key> pairs encrypting sessions and API tokens, newest first (default random, logging users out and invalidating API tokens on restart)", secret: true},
+	{key: "session_max_age", env: "SESSION_MAX_AGE", def: "1h", usage: "how long a login lasts"},
+	{key: "login_state_ttl", env: "LOGIN_STATE_TTL", def: "10m", usage: "how long a user has to complete a login"},
+	{key: "lateness_timezone", env: "LATENESS_TIMEZONE", def: "UTC", usage: "IANA time zone in which due dates are interpreted"},
+	{key: "lateness_deadline", env: "LATENESS_DEADLINE", def: "24:00", usage: "time of day, HH:MM, by which tasks must be completed on their due date"},
+	{key: "lateness_grace_period", env: "LATENESS_GRACE_PERIOD", def: "0s", usage: "how long after the deadline tasks are still on time"},
+	{key: "lateness_resolution", env: "LATENESS_RESOLUTION", def: "day", usage: "lateness resolution: day or hour"},
+	{key: "lateness_mode", env: "LATENESS_MODE", def: "calendar", usage: "days counted for lateness: calendar or business"},
+	{key: "lateness_weekend", env: "LATENESS_WEEKEND", def: "saturday,sunday", usage: "comma-separated weekday names not counted in business mode"},
+	{key: "lateness_holidays_file", env: "LATENESS_HOLIDAYS_FILE", usage: "iCalendar (.ics) or JSON file of holidays not counted in business mode"},
+}
+
+// fileEnv and fileFlag locate the config file, and so
+// cannot themselves be set in it.
+const (
+	fileEnv  = "TARDY_CONFIG"
+	fileFlag = "config"
+)
@@ -13,6 +13,7 @@ var (
 	filenames = []string{
 		"/templates/head.html.tmpl",
 		"/templates/home.html.tmpl",
+		"/templates/error.html.tmpl",
 	}
 )
 
@@ -0,0 +1,29 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces the file at path with data, readable only by
+// its owner. The data is written to a temporary file which is renamed
+// into place, so that a crash part-way through never leaves a truncated
+// file behind.
+func WriteFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
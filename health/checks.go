@@ -0,0 +1,87 @@
+package health
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Check returns an error if the dependency it checks is not ready.
+type Check func() error
+
+// TemplatesLoaded checks that each of the named templates is defined.
+func TemplatesLoaded(templates *template.Template, names ...string) Check {
+	return func() error {
+		if templates == nil {
+			return fmt.Errorf("templates not loaded")
+		}
+		for _, name := range names {
+			if templates.Lookup(name) == nil {
+				return fmt.Errorf("template %q not loaded", name)
+			}
+		}
+		return nil
+	}
+}
+
+// DirWritable checks that a file can be created in dir.
+func DirWritable(dir string) Check {
+	return func() error {
+		f, err := ioutil.TempFile(dir, "readyz-")
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write([]byte("ok"))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		os.Remove(f.Name())
+		return err
+	}
+}
+
+// URLReachable checks that a GET of url gets a response other than a
+// server error. Client errors such as 401 still show that it is up.
+func URLReachable(url string, timeout time.Duration) Check {
+	client := &http.Client{Timeout: timeout}
+
+	return func() error {
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Cached runs check at most once per ttl, returning the previous
+// result in between, so that frequent probes do not overload
+// external dependencies.
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mu        sync.Mutex
+		checkedAt time.Time
+		result    error
+	)
+
+	return func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if checkedAt.IsZero() || time.Since(checkedAt) >= ttl {
+			result = check()
+			checkedAt = time.Now()
+		}
+		return result
+	}
+}
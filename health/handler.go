@@ -0,0 +1,93 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/middleware"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// NamedCheck is a readiness check and the name it is reported under.
+type NamedCheck struct {
+	Name  string
+	Check Check
+}
+
+type Handler interface {
+	Healthz(w http.ResponseWriter, r *http.Request)
+	Readyz(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger lager.Logger
+	checks []NamedCheck
+}
+
+// NewHandler returns a handler for liveness and readiness probes.
+// Readiness requires every check to pass.
+func NewHandler(logger lager.Logger, checks []NamedCheck) Handler {
+	return &handler{
+		logger: logger.Session("health"),
+		checks: checks,
+	}
+}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type response struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// Healthz reports that the process is alive and serving requests.
+func (h handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.writeResponse(w, http.StatusOK, response{Status: statusOK})
+}
+
+// Readyz runs every check, reporting the result of each, and
+// responds with 503 if any failed.
+func (h handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	resp := response{
+		Status: statusOK,
+		Checks: map[string]checkResult{},
+	}
+
+	for _, c := range h.checks {
+		err := c.Check()
+		if err != nil {
+			h.logger.Info("readiness check failed", lager.Data{"check": c.Name, "error": err.Error()})
+			resp.Status = statusUnavailable
+			resp.Checks[c.Name] = checkResult{Status: statusUnavailable, Error: err.Error()}
+			continue
+		}
+		resp.Checks[c.Name] = checkResult{Status: statusOK}
+	}
+
+	status := http.StatusOK
+	if resp.Status != statusOK {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeResponse(w, status, resp)
+}
+
+func (h handler) writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		h.logger.Error("failed to serialize health", err)
+	}
+}
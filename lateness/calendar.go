@@ -0,0 +1,221 @@
+package lateness
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// Calendar is a set of holidays, which are not working days.
+// The zero value contains no holidays.
+type Calendar struct {
+	dates map[string]bool
+
+	// yearly maps the month and day of recurring holidays
+	// to the first year in which they occur.
+	yearly map[string]int
+}
+
+// Contains reports whether the calendar date of t is a holiday.
+func (c Calendar) Contains(t time.Time) bool {
+	if c.dates[t.Format(dateFormat)] {
+		return true
+	}
+
+	if firstYear, ok := c.yearly[t.Format("01-02")]; ok && t.Year() >= firstYear {
+		return true
+	}
+
+	return false
+}
+
+func (c *Calendar) addDate(t time.Time) {
+	if c.dates == nil {
+		c.dates = map[string]bool{}
+	}
+	c.dates[t.Format(dateFormat)] = true
+}
+
+func (c *Calendar) addYearly(t time.Time) {
+	if c.yearly == nil {
+		c.yearly = map[string]int{}
+	}
+	monthDay := t.Format("01-02")
+	if firstYear, ok := c.yearly[monthDay]; !ok || t.Year() < firstYear {
+		c.yearly[monthDay] = t.Year()
+	}
+}
+
+// LoadCalendar reads holidays from an iCalendar (.ics) or JSON (.json) file.
+func LoadCalendar(path string) (Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Calendar{}, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical":
+		return ParseICalendar(f)
+	case ".json":
+		return ParseJSONCalendar(f)
+	default:
+		return Calendar{}, fmt.Errorf("holiday calendar must be a .ics or .json file, got %s", path)
+	}
+}
+
+// ParseJSONCalendar reads holidays from a JSON array of YYYY-MM-DD dates,
+// or of objects with a "date" and optional "name" field.
+func ParseJSONCalendar(r io.Reader) (Calendar, error) {
+	var entries []json.RawMessage
+	err := json.NewDecoder(r).Decode(&entries)
+	if err != nil {
+		return Calendar{}, err
+	}
+
+	var c Calendar
+	for _, e := range entries {
+		var date string
+		if json.Unmarshal(e, &date) != nil {
+			var holiday struct {
+				Date string `json:"date"`
+				Name string `json:"name"`
+			}
+			err := json.Unmarshal(e, &holiday)
+			if err != nil {
+				return Calendar{}, fmt.Errorf("holiday must be a date or an object with a date: %s", string(e))
+			}
+			date = holiday.Date
+		}
+
+		t, err := time.Parse(dateFormat, date)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("holiday date must be in the form YYYY-MM-DD, got %q", date)
+		}
+		c.addDate(t)
+	}
+
+	return c, nil
+}
+
+// ParseICalendar reads holidays from the all-day and timed events in an
+// iCalendar stream. Every date an event spans is a holiday, and events
+// with a yearly recurrence rule repeat from their first occurrence.
+// Other recurrence rules are not supported and are treated as single events.
+func ParseICalendar(r io.Reader) (Calendar, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return Calendar{}, err
+	}
+
+	var c Calendar
+	var inEvent, yearly bool
+	var start, end time.Time
+
+	for _, line := range lines {
+		name, value := splitProperty(line)
+
+		switch name {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, yearly = true, false
+				start, end = time.Time{}, time.Time{}
+			}
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+
+			if start.IsZero() {
+				return Calendar{}, fmt.Errorf("event without DTSTART in holiday calendar")
+			}
+			if end.IsZero() || !end.After(start) {
+				// DTEND is exclusive; a missing one means a single day.
+				end = start.AddDate(0, 0, 1)
+			}
+
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				if yearly {
+					c.addYearly(d)
+				} else {
+					c.addDate(d)
+				}
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			t, err := parseICalendarDate(value)
+			if err != nil {
+				return Calendar{}, err
+			}
+			if name == "DTSTART" {
+				start = t
+			} else {
+				end = t
+			}
+		case "RRULE":
+			if inEvent && strings.Contains(value, "FREQ=YEARLY") {
+				yearly = true
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// unfoldLines joins continuation lines, which begin with whitespace,
+// onto the line before them.
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// splitProperty returns the name of a content line, without parameters,
+// and its value.
+func splitProperty(line string) (string, string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", ""
+	}
+
+	name := line[:i]
+	if j := strings.Index(name, ";"); j >= 0 {
+		name = name[:j]
+	}
+
+	return strings.ToUpper(name), strings.TrimSpace(line[i+1:])
+}
+
+// parseICalendarDate returns the calendar date of a DATE or DATE-TIME value.
+func parseICalendarDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date in holiday calendar: %q", value)
+	}
+
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date in holiday calendar: %q", value)
+	}
+
+	return t, nil
+}
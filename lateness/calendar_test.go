@@ -0,0 +1,158 @@
+package lateness
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func assertHolidays(t *testing.T, c Calendar, holidays []time.Time, workingDays []time.Time) {
+	for _, d := range holidays {
+		if !c.Contains(d) {
+			t.Errorf("expected %s to be a holiday", d.Format(dateFormat))
+		}
+	}
+	for _, d := range workingDays {
+		if c.Contains(d) {
+			t.Errorf("expected %s not to be a holiday", d.Format(dateFormat))
+		}
+	}
+}
+
+func TestParseICalendar(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Christmas Day",
+		"DTSTART;VALUE=DATE:20151225",
+		"DTEND;VALUE=DATE:20151226",
+		"RRULE:FREQ=YEARLY",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Company",
+		" offsite",
+		"DTSTART;VALUE=DATE:20160302",
+		"DTEND;VALUE=DATE:20160304",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Timed event without an end",
+		"DTSTART;TZID=Europe/London:20160401T090000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	c, err := ParseICalendar(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("failed to parse calendar: %s", err)
+	}
+
+	assertHolidays(t, c,
+		[]time.Time{
+			date(2015, 12, 25),
+			date(2016, 12, 25),
+			date(2030, 12, 25),
+			date(2016, 3, 2),
+			date(2016, 3, 3),
+			date(2016, 4, 1),
+		},
+		[]time.Time{
+			date(2014, 12, 25),
+			date(2015, 12, 26),
+			date(2016, 3, 4),
+			date(2017, 3, 2),
+			date(2016, 4, 2),
+		},
+	)
+}
+
+func TestParseICalendarRejectsMalformedEvents(t *testing.T) {
+	for _, ics := range []string{
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:2016\nEND:VEVENT\n",
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:2016XX01\nEND:VEVENT\n",
+		"BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\n",
+	} {
+		_, err := ParseICalendar(strings.NewReader(ics))
+		if err == nil {
+			t.Errorf("expected an error parsing %q", ics)
+		}
+	}
+
+	// Lines without a property, and dates outside events, are ignored.
+	c, err := ParseICalendar(strings.NewReader("not a property\nDTSTART:garbage\nBEGIN:VEVENT\nDTSTART:20160101\nEND:VEVENT\n"))
+	if err != nil {
+		t.Fatalf("failed to parse calendar: %s", err)
+	}
+	assertHolidays(t, c, []time.Time{date(2016, 1, 1)}, nil)
+}
+
+func TestParseJSONCalendar(t *testing.T) {
+	c, err := ParseJSONCalendar(strings.NewReader(`["2016-01-01", {"date": "2016-05-30", "name": "Memorial Day"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse calendar: %s", err)
+	}
+
+	assertHolidays(t, c,
+		[]time.Time{date(2016, 1, 1), date(2016, 5, 30)},
+		[]time.Time{date(2017, 1, 1), date(2016, 5, 31)},
+	)
+
+	for _, json := range []string{
+		`{"date": "2016-01-01"}`,
+		`["01/01/2016"]`,
+		`[7]`,
+		`[{"date": "2016-13-01"}]`,
+	} {
+		_, err := ParseJSONCalendar(strings.NewReader(json))
+		if err == nil {
+			t.Errorf("expected an error parsing %s", json)
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tardy-calendar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"holidays.ICS":  "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20160101\nEND:VEVENT\n",
+		"holidays.json": `["2016-01-01"]`,
+	} {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := LoadCalendar(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %s", name, err)
+		}
+		assertHolidays(t, c, []time.Time{date(2016, 1, 1)}, nil)
+	}
+
+	_, err = LoadCalendar(filepath.Join(dir, "holidays.txt"))
+	if err == nil {
+		t.Errorf("expected an error loading a file which does not exist")
+	}
+
+	path := filepath.Join(dir, "holidays.csv")
+	err = ioutil.WriteFile(path, []byte("2016-01-01\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadCalendar(path)
+	if err == nil {
+		t.Errorf("expected an error loading a calendar of an unknown format")
+	}
+}
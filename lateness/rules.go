@@ -0,0 +1,275 @@
+package lateness
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Resolution string
+
+const (
+	// ResolutionDay counts the calendar days, in the rules' location,
+	// between the due date and the date of completion.
+	ResolutionDay Resolution = "day"
+
+	// ResolutionHour counts the started 24-hour periods
+	// between the deadline and the completion.
+	ResolutionHour Resolution = "hour"
+)
+
+type Mode string
+
+const (
+	// ModeCalendar counts every day.
+	ModeCalendar Mode = "calendar"
+
+	// ModeBusiness counts only working days, skipping weekends
+	// and holidays. It requires ResolutionDay.
+	ModeBusiness Mode = "business"
+)
+
+// EndOfDay is the Deadline for tasks which may be completed
+// at any time on their due date.
+const EndOfDay = 24 * time.Hour
+
+// Rules determine how late a task was completed relative to its due date.
+type Rules struct {
+	// Location is the time zone in which due dates are interpreted.
+	Location *time.Location
+
+	// Deadline is the time of day on the due date by which
+	// a task must be completed, as an offset from midnight.
+	Deadline time.Duration
+
+	// GracePeriod is how long after the deadline a task may be
+	// completed and still be considered on time.
+	GracePeriod time.Duration
+
+	Resolution Resolution
+
+	Mode Mode
+
+	// Weekend and Holidays are the days which are not counted
+	// in ModeBusiness.
+	Weekend  map[time.Weekday]bool
+	Holidays Calendar
+}
+
+// Lateness is how late a task was completed.
+// Both values are negative for tasks completed early.
+type Lateness struct {
+	// Days is rounded according to the rules' Resolution:
+	// late tasks are at least one day late, and tasks completed
+	// within the grace period are zero days late.
+	Days int
+
+	// Duration is the precise time between the deadline and completion,
+	// regardless of any grace period.
+	Duration time.Duration
+}
+
+// DefaultRules allow tasks to be completed at any time on
+// their due date in UTC, with no grace period, counting every day.
+// If business days are selected, weekends are Saturday and Sunday.
+func DefaultRules() Rules {
+	return Rules{
+		Location:   time.UTC,
+		Deadline:   EndOfDay,
+		Resolution: ResolutionDay,
+		Mode:       ModeCalendar,
+		Weekend: map[time.Weekday]bool{
+			time.Saturday: true,
+			time.Sunday:   true,
+		},
+	}
+}
+
+func (r Rules) Validate() error {
+	var problems []string
+
+	if r.Location == nil {
+		problems = append(problems, "location must be provided")
+	}
+
+	if r.Deadline < 0 || r.Deadline > EndOfDay {
+		problems = append(problems, fmt.Sprintf("deadline must be between 00:00 and 24:00, got %s", r.Deadline))
+	}
+
+	if r.GracePeriod < 0 {
+		problems = append(problems, fmt.Sprintf("grace period must not be negative, got %s", r.GracePeriod))
+	}
+
+	if r.Resolution != ResolutionDay && r.Resolution != ResolutionHour {
+		problems = append(problems, fmt.Sprintf("resolution must be %s or %s, got %q", ResolutionDay, ResolutionHour, r.Resolution))
+	}
+
+	switch r.Mode {
+	case ModeCalendar:
+	case ModeBusiness:
+		if r.Resolution != ResolutionDay {
+			problems = append(problems, fmt.Sprintf("mode %s requires resolution %s", ModeBusiness, ResolutionDay))
+		}
+		if len(r.Weekend) >= 7 {
+			problems = append(problems, "weekend must leave at least one working day")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("mode must be %s or %s, got %q", ModeCalendar, ModeBusiness, r.Mode))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid lateness rules: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Calculate returns the lateness of a task completed at completedAt.
+// Only the calendar date of dueDate is used.
+func (r Rules) Calculate(dueDate time.Time, completedAt time.Time) Lateness {
+	location := r.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	year, month, day := dueDate.Date()
+	// Normalizing the seconds rather than adding a Duration keeps the
+	// deadline at the same wall-clock time across daylight saving changes.
+	deadline := time.Date(year, month, day, 0, 0, int(r.Deadline/time.Second), 0, location)
+	effectiveDeadline := deadline.Add(r.GracePeriod)
+	completed := completedAt.In(location)
+
+	l := Lateness{
+		Duration: completed.Sub(deadline),
+	}
+
+	// Noon avoids any ambiguity around midnight and daylight saving.
+	dueDay := time.Date(year, month, day, 12, 0, 0, 0, location)
+
+	switch {
+	case completed.After(effectiveDeadline):
+		l.Days = r.daysLate(dueDay, effectiveDeadline, completed)
+	case l.Duration < 0:
+		l.Days = -r.daysEarly(dueDay, deadline, completed)
+	}
+
+	return l
+}
+
+// daysLate returns the number of days, or working days in ModeBusiness,
+// after the due date up to and including the day of completion, and at
+// least one. For ResolutionHour it is the number of started 24-hour
+// periods after the deadline.
+func (r Rules) daysLate(dueDay time.Time, deadline time.Time, completed time.Time) int {
+	if r.Resolution == ResolutionHour {
+		return int((completed.Sub(deadline) + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+
+	days := calendarDays(dueDay, completed)
+	if r.Mode == ModeBusiness {
+		days = r.workingDays(dueDay, completed)
+	}
+
+	// Tasks completed late on their due date are a day late.
+	if days < 1 {
+		days = 1
+	}
+	return days
+}
+
+// daysEarly returns the number of whole days before the deadline.
+// For ResolutionDay it is the number of days, or working days in
+// ModeBusiness, after the day of completion up to and including the due date.
+func (r Rules) daysEarly(dueDay time.Time, deadline time.Time, completed time.Time) int {
+	if r.Resolution == ResolutionHour {
+		return int(deadline.Sub(completed) / (24 * time.Hour))
+	}
+
+	if r.Mode == ModeBusiness {
+		return r.workingDays(completed, dueDay)
+	}
+	return calendarDays(completed, dueDay)
+}
+
+// workingDays returns the number of working days after the calendar
+// date of a, up to and including the calendar date of b.
+func (r Rules) workingDays(a time.Time, b time.Time) int {
+	n := calendarDays(a, b)
+
+	ay, am, ad := a.Date()
+	days := 0
+	for i := 1; i <= n; i++ {
+		d := time.Date(ay, am, ad+i, 12, 0, 0, 0, time.UTC)
+		if !r.Weekend[d.Weekday()] && !r.Holidays.Contains(d) {
+			days++
+		}
+	}
+	return days
+}
+
+// calendarDays returns the number of calendar days from a to b,
+// using the dates in each time's own location.
+func calendarDays(a time.Time, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+
+	// Noon avoids any ambiguity around midnight and daylight saving.
+	aNoon := time.Date(ay, am, ad, 12, 0, 0, 0, time.UTC)
+	bNoon := time.Date(by, bm, bd, 12, 0, 0, 0, time.UTC)
+
+	return int(bNoon.Sub(aNoon) / (24 * time.Hour))
+}
+
+// ParseWeekend parses a comma-separated list of weekday names,
+// e.g. "saturday,sunday". An empty string means no weekend.
+func ParseWeekend(s string) (map[time.Weekday]bool, error) {
+	weekend := map[time.Weekday]bool{}
+
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+				weekend[d] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("weekend must be a comma-separated list of weekday names, got %q", name)
+		}
+	}
+
+	return weekend, nil
+}
+
+// ParseDeadline parses a time of day in the form HH:MM,
+// from 00:00 to 24:00 inclusive.
+func ParseDeadline(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("deadline must be in the form HH:MM, got %q", s)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("deadline must be in the form HH:MM, got %q", s)
+	}
+
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("deadline must be in the form HH:MM, got %q", s)
+	}
+
+	deadline := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if hours < 0 || deadline > EndOfDay {
+		return 0, fmt.Errorf("deadline must be between 00:00 and 24:00, got %q", s)
+	}
+
+	return deadline, nil
+}
@@ -0,0 +1,171 @@
+package lateness
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %s", name, err)
+	}
+	return location
+}
+
+func TestCalculateCountsCalendarDays(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	rules := DefaultRules()
+	rules.Location = newYork
+	rules.Deadline = 17 * time.Hour
+
+	// Daylight saving starts in New York on Sunday 13 March 2016.
+	dueDate := time.Date(2016, 3, 12, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		completedAt time.Time
+		days        int
+		duration    time.Duration
+	}{
+		{time.Date(2016, 3, 12, 16, 0, 0, 0, newYork), 0, -time.Hour},
+		{time.Date(2016, 3, 12, 18, 0, 0, 0, newYork), 1, time.Hour},
+		{time.Date(2016, 3, 13, 18, 0, 0, 0, newYork), 1, 24 * time.Hour},
+		{time.Date(2016, 3, 13, 23, 59, 0, 0, newYork), 1, 29*time.Hour + 59*time.Minute},
+		{time.Date(2016, 3, 14, 0, 30, 0, 0, newYork), 2, 30*time.Hour + 30*time.Minute},
+		{time.Date(2016, 3, 11, 18, 0, 0, 0, newYork), -1, -23 * time.Hour},
+	} {
+		l := rules.Calculate(dueDate, test.completedAt)
+		if l.Days != test.days || l.Duration != test.duration {
+			t.Errorf("completed at %s: expected %d days and %s, got %d days and %s",
+				test.completedAt, test.days, test.duration, l.Days, l.Duration)
+		}
+	}
+}
+
+func TestCalculateCountsStartedPeriodsForHourResolution(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	rules := DefaultRules()
+	rules.Location = newYork
+	rules.Deadline = 17 * time.Hour
+	rules.Resolution = ResolutionHour
+
+	dueDate := time.Date(2016, 3, 12, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		completedAt time.Time
+		days        int
+	}{
+		{time.Date(2016, 3, 13, 17, 0, 0, 0, newYork), 1},
+		{time.Date(2016, 3, 13, 18, 0, 0, 0, newYork), 1},
+		{time.Date(2016, 3, 13, 18, 1, 0, 0, newYork), 2},
+		{time.Date(2016, 3, 10, 16, 0, 0, 0, newYork), -2},
+	} {
+		l := rules.Calculate(dueDate, test.completedAt)
+		if l.Days != test.days {
+			t.Errorf("completed at %s: expected %d days, got %d", test.completedAt, test.days, l.Days)
+		}
+	}
+}
+
+func TestCalculateEndOfDay(t *testing.T) {
+	rules := DefaultRules()
+	dueDate := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		completedAt time.Time
+		days        int
+	}{
+		{time.Date(2016, 3, 1, 23, 59, 0, 0, time.UTC), 0},
+		{time.Date(2016, 3, 2, 0, 1, 0, 0, time.UTC), 1},
+		{time.Date(2016, 3, 4, 12, 0, 0, 0, time.UTC), 3},
+		{time.Date(2016, 2, 28, 12, 0, 0, 0, time.UTC), -2},
+	} {
+		l := rules.Calculate(dueDate, test.completedAt)
+		if l.Days != test.days {
+			t.Errorf("completed at %s: expected %d days, got %d", test.completedAt, test.days, l.Days)
+		}
+	}
+}
+
+func TestCalculateBusinessDays(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	holidays, err := ParseJSONCalendar(strings.NewReader(`["2016-03-14"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := DefaultRules()
+	rules.Mode = ModeBusiness
+	rules.Location = newYork
+	rules.Deadline = 17 * time.Hour
+	rules.GracePeriod = 2 * time.Hour
+	rules.Holidays = holidays
+
+	// Friday 11 March 2016. The weekend is followed by a holiday on Monday.
+	dueDate := time.Date(2016, 3, 11, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		completedAt time.Time
+		days        int
+	}{
+		// Within the grace period.
+		{time.Date(2016, 3, 11, 18, 59, 0, 0, newYork), 0},
+		// After the grace period on the due date.
+		{time.Date(2016, 3, 11, 19, 30, 0, 0, newYork), 1},
+		// The weekend and the holiday are not counted.
+		{time.Date(2016, 3, 12, 9, 0, 0, 0, newYork), 1},
+		{time.Date(2016, 3, 14, 9, 0, 0, 0, newYork), 1},
+		{time.Date(2016, 3, 15, 9, 0, 0, 0, newYork), 1},
+		{time.Date(2016, 3, 16, 9, 0, 0, 0, newYork), 2},
+		// Completion times are converted to the rules' location: this
+		// is the evening of Tuesday 15 March in New York.
+		{time.Date(2016, 3, 16, 1, 0, 0, 0, time.UTC), 1},
+		// Early tasks count the working days up to the due date.
+		{time.Date(2016, 3, 10, 9, 0, 0, 0, newYork), -1},
+		{time.Date(2016, 3, 6, 9, 0, 0, 0, newYork), -5},
+	} {
+		l := rules.Calculate(dueDate, test.completedAt)
+		if l.Days != test.days {
+			t.Errorf("completed at %s: expected %d days, got %d", test.completedAt, test.days, l.Days)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := DefaultRules().Validate(); err != nil {
+		t.Errorf("expected the default rules to be valid, got %s", err)
+	}
+
+	rules := DefaultRules()
+	rules.Mode = ModeBusiness
+	rules.Resolution = ResolutionHour
+	if rules.Validate() == nil {
+		t.Errorf("expected business mode to require day resolution")
+	}
+
+	rules = DefaultRules()
+	rules.Mode = ModeBusiness
+	rules.Weekend, _ = ParseWeekend("mon,tue,wed,thu,fri,sat,sun")
+	if rules.Validate() == nil {
+		t.Errorf("expected business mode to require a working day")
+	}
+}
+
+func TestParseWeekend(t *testing.T) {
+	weekend, err := ParseWeekend("Friday, sat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weekend) != 2 || !weekend[time.Friday] || !weekend[time.Saturday] {
+		t.Errorf("expected Friday and Saturday, got %v", weekend)
+	}
+
+	_, err = ParseWeekend("saturday,caturday")
+	if err == nil {
+		t.Errorf("expected an error for an unknown weekday")
+	}
+}
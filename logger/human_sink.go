@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+type humanSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewHumanSink returns a sink which writes each log as a single line of
+// the form "<time> <LEVEL> <message> key=value ...", with keys sorted.
+func NewHumanSink(w io.Writer) lager.Sink {
+	return &humanSink{
+		w: w,
+	}
+}
+
+func (s *humanSink) Log(level lager.LogLevel, payload []byte) {
+	var log lager.LogFormat
+	err := json.Unmarshal(payload, &log)
+	if err != nil {
+		s.write(payload)
+		return
+	}
+
+	var b bytes.Buffer
+	b.WriteString(formatTimestamp(log.Timestamp))
+	b.WriteByte(' ')
+	fmt.Fprintf(&b, "%-5s", strings.ToUpper(string(Level(level))))
+	b.WriteByte(' ')
+	b.WriteString(log.Message)
+
+	keys := make([]string, 0, len(log.Data))
+	for k := range log.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b.WriteByte(' ')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(formatValue(log.Data[k]))
+	}
+	b.WriteByte('\n')
+
+	s.write(b.Bytes())
+}
+
+func (s *humanSink) write(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(b)
+}
+
+// formatTimestamp converts lager's seconds since the epoch to RFC 3339.
+func formatTimestamp(timestamp string) string {
+	seconds, err := strconv.ParseFloat(timestamp, 64)
+	if err != nil {
+		return timestamp
+	}
+
+	whole := int64(seconds)
+	nanos := int64((seconds - float64(whole)) * 1e9)
+	return time.Unix(whole, nanos).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func formatValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		s = string(b)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -2,7 +2,7 @@ package logger
 
 import (
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/pivotal-golang/lager"
 )
@@ -17,25 +17,80 @@ const (
 	LogLevelFatal   LogLevel = "fatal"
 )
 
-func InitializeLogger(minLogLevel LogLevel) (lager.Logger, *lager.ReconfigurableSink, error) {
-	var minLagerLogLevel lager.LogLevel
-	switch minLogLevel {
-	case LogLevelDebug:
-		minLagerLogLevel = lager.DEBUG
-	case LogLevelInfo:
-		minLagerLogLevel = lager.INFO
-	case LogLevelError:
-		minLagerLogLevel = lager.ERROR
-	case LogLevelFatal:
-		minLagerLogLevel = lager.FATAL
+type Format string
+
+const (
+	// FormatJSON writes one lager JSON object per line.
+	FormatJSON Format = "json"
+
+	// FormatHuman writes one readable line per log.
+	FormatHuman Format = "human"
+)
+
+// levels are ordered from most to least verbose.
+var levels = []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelError, LogLevelFatal}
+
+// LagerLogLevel returns the lager.LogLevel for the provided level.
+func LagerLogLevel(level LogLevel) (lager.LogLevel, error) {
+	for i, l := range levels {
+		if l == level {
+			return lager.LogLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level: %s", level)
+}
+
+// Level returns the LogLevel for the provided lager.LogLevel.
+func Level(level lager.LogLevel) LogLevel {
+	if level < 0 || int(level) >= len(levels) {
+		return LogLevelInvalid
+	}
+	return levels[level]
+}
+
+// InitializeLogger returns a logger writing to w in the provided format,
+// with the values of keys redacted by redactor scrubbed from every log.
+// The returned sink may be used to change the minimum log level at runtime.
+func InitializeLogger(minLogLevel LogLevel, format Format, w io.Writer, redactor Redactor) (lager.Logger, *lager.ReconfigurableSink, error) {
+	minLagerLogLevel, err := LagerLogLevel(minLogLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var s lager.Sink
+	switch format {
+	case FormatJSON:
+		s = lager.NewWriterSink(w, lager.DEBUG)
+	case FormatHuman:
+		s = NewHumanSink(w)
 	default:
-		return nil, nil, fmt.Errorf("unknown log level: %s", minLogLevel)
+		return nil, nil, fmt.Errorf("unknown log format: %s", format)
 	}
 
 	logger := lager.NewLogger("tardy")
 
-	sink := lager.NewReconfigurableSink(lager.NewWriterSink(os.Stdout, lager.DEBUG), minLagerLogLevel)
+	sink := lager.NewReconfigurableSink(NewRedactingSink(s, redactor), minLagerLogLevel)
 	logger.RegisterSink(sink)
 
 	return logger, sink, nil
 }
+
+// Raise makes the sink one level more verbose, returning the new level.
+func Raise(sink *lager.ReconfigurableSink) LogLevel {
+	level := sink.GetMinLevel()
+	if level > lager.DEBUG {
+		level--
+		sink.SetMinLevel(level)
+	}
+	return Level(level)
+}
+
+// Lower makes the sink one level less verbose, returning the new level.
+func Lower(sink *lager.ReconfigurableSink) LogLevel {
+	level := sink.GetMinLevel()
+	if level < lager.FATAL {
+		level++
+		sink.SetMinLevel(level)
+	}
+	return Level(level)
+}
@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// Redacted replaces the value of every redacted key.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedKeys are the keys whose values are never logged.
+// Keys are matched ignoring case, dashes and underscores, so
+// "accessToken", "access_token" and "Access-Token" are all redacted.
+var DefaultRedactedKeys = []string{
+	"access_token",
+	"authorization",
+	"client_secret",
+	"code",
+	"cookie",
+	"csrf_token",
+	"password",
+	"secret",
+	"session_keys",
+	"set_cookie",
+	"state",
+	"token",
+	"webhook_secret",
+}
+
+// Redactor scrubs the values of configured keys from log data,
+// headers and URLs, and secrets carried in configured URL paths.
+type Redactor struct {
+	keys        map[string]bool
+	secretPaths [][]string
+}
+
+// NewRedactor returns a Redactor for the DefaultRedactedKeys
+// and any extra keys provided.
+func NewRedactor(extraKeys ...string) Redactor {
+	r := Redactor{
+		keys: map[string]bool{},
+	}
+	for _, k := range append(DefaultRedactedKeys, extraKeys...) {
+		r.keys[normalizeKey(k)] = true
+	}
+	return r
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.Replace(key, "-", "", -1)
+	return strings.Replace(key, "_", "", -1)
+}
+
+// WithSecretPath returns a copy of r which also redacts the segments
+// of paths matching pattern, such as "/webhooks/wunderlist/*/{secret}".
+// Each segment of pattern matches the same segment literally, except
+// "*", which matches any segment, and "{secret}", which matches and
+// redacts any segment. Paths with more segments match by their prefix.
+func (r Redactor) WithSecretPath(pattern string) Redactor {
+	// The patterns are copied so that r is not affected.
+	secretPaths := make([][]string, 0, len(r.secretPaths)+1)
+	secretPaths = append(secretPaths, r.secretPaths...)
+	r.secretPaths = append(secretPaths, strings.Split(pattern, "/"))
+	return r
+}
+
+// Path returns path with the segments matched by secret paths replaced.
+func (r Redactor) Path(path string) string {
+	if len(r.secretPaths) == 0 || !strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	redacted := false
+	for _, pattern := range r.secretPaths {
+		if !matchesPath(pattern, segments) {
+			continue
+		}
+		for i, p := range pattern {
+			if p == "{secret}" {
+				segments[i] = Redacted
+				redacted = true
+			}
+		}
+	}
+
+	if !redacted {
+		return path
+	}
+	return strings.Join(segments, "/")
+}
+
+func matchesPath(pattern []string, segments []string) bool {
+	if len(segments) < len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != "{secret}" && p != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Redacts reports whether the value of key is redacted.
+func (r Redactor) Redacts(key string) bool {
+	return r.keys[normalizeKey(key)]
+}
+
+// Header returns a copy of h with the values of redacted keys replaced.
+func (r Redactor) Header(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+
+	redacted := make(http.Header, len(h))
+	for k, v := range h {
+		if r.Redacts(k) {
+			v = []string{Redacted}
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// Values returns a copy of v with the values of redacted keys replaced.
+func (r Redactor) Values(v url.Values) url.Values {
+	if v == nil {
+		return nil
+	}
+
+	redacted := make(url.Values, len(v))
+	for k, values := range v {
+		if r.Redacts(k) {
+			values = []string{Redacted}
+		}
+		redacted[k] = values
+	}
+	return redacted
+}
+
+// URL returns a copy of u with secret path segments and the values of
+// redacted query parameters replaced, and any user info removed.
+func (r Redactor) URL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+
+	redacted := *u
+	redacted.User = nil
+	redacted.Path = r.Path(u.Path)
+	// RawPath is ignored, and Path escaped, unless it matches Path.
+	redacted.RawPath = r.Path(u.EscapedPath())
+	if u.RawQuery != "" {
+		redacted.RawQuery = r.Values(u.Query()).Encode()
+	}
+	return &redacted
+}
+
+// Data returns a copy of data with the values of redacted keys replaced,
+// at any depth, as are secret segments of any paths it holds.
+func (r Redactor) Data(data lager.Data) lager.Data {
+	if data == nil {
+		return nil
+	}
+	return lager.Data(r.scrubMap(data))
+}
+
+// Value returns a copy of v with the values of redacted keys replaced,
+// at any depth, where v is a value decoded by encoding/json.
+func (r Redactor) Value(v interface{}) interface{} {
+	return r.scrub(v)
+}
+
+func (r Redactor) scrubMap(m map[string]interface{}) map[string]interface{} {
+	scrubbed := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if r.Redacts(k) && v != nil && v != "" {
+			scrubbed[k] = Redacted
+		} else {
+			scrubbed[k] = r.scrub(v)
+		}
+	}
+	return scrubbed
+}
+
+func (r Redactor) scrub(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return r.scrubMap(v)
+	case lager.Data:
+		return r.scrubMap(v)
+	case []interface{}:
+		scrubbed := make([]interface{}, len(v))
+		for i, e := range v {
+			scrubbed[i] = r.scrub(e)
+		}
+		return scrubbed
+	case string:
+		return r.Path(v)
+	default:
+		return v
+	}
+}
+
+type redactingSink struct {
+	sink     lager.Sink
+	redactor Redactor
+}
+
+// NewRedactingSink returns a sink which scrubs redacted keys from each
+// log's data, including within nested values, before passing it to sink.
+// Logs which cannot be parsed are dropped rather than risk leaking them.
+func NewRedactingSink(sink lager.Sink, redactor Redactor) lager.Sink {
+	return redactingSink{
+		sink:     sink,
+		redactor: redactor,
+	}
+}
+
+func (s redactingSink) Log(level lager.LogLevel, payload []byte) {
+	var log lager.LogFormat
+	err := json.Unmarshal(payload, &log)
+	if err != nil {
+		return
+	}
+
+	log.Data = s.redactor.Data(log.Data)
+
+	payload, err = json.Marshal(log)
+	if err != nil {
+		return
+	}
+
+	s.sink.Log(level, payload)
+}
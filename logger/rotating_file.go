@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile returns a writer which appends to the file at path.
+// Before a write would take the file over maxSize bytes, the file is
+// renamed to path.1, any existing path.1 to path.2 and so on, keeping
+// at most maxBackups old files, and a new file is started.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+
+	if f.maxBackups > 0 {
+		os.Remove(backupPath(f.path, f.maxBackups))
+		for i := f.maxBackups - 1; i >= 1; i-- {
+			os.Rename(backupPath(f.path, i), backupPath(f.path, i+1))
+		}
+		err = os.Rename(f.path, backupPath(f.path, 1))
+	} else {
+		err = os.Remove(f.path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return f.open()
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// HandleSignals makes the sink more verbose on SIGUSR1
+// and less verbose on SIGUSR2.
+func HandleSignals(logger lager.Logger, sink *lager.ReconfigurableSink) {
+	logger = logger.Session("log-level-signals")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for s := range signals {
+			var level LogLevel
+			if s == syscall.SIGUSR1 {
+				level = Raise(sink)
+			} else {
+				level = Lower(sink)
+			}
+
+			// Logged at error level so that it is seen at any level but fatal.
+			logger.Error("log-level-changed", nil, lager.Data{"signal": s.String(), "level": level})
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// ContentType is the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type handler struct {
+	logger   lager.Logger
+	registry *Registry
+	token    string
+}
+
+// NewHandler serves the registry's metrics to scrapers which provide
+// the token in an "Authorization: Bearer" header.
+func NewHandler(logger lager.Logger, registry *Registry, token string) http.Handler {
+	return &handler{
+		logger:   logger.Session("metrics"),
+		registry: registry,
+		token:    token,
+	}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimSpace(header[len("Bearer "):])), []byte(h.token)) != 1 {
+		h.logger.Info("rejecting scrape without valid token", lager.Data{"remote-addr": r.RemoteAddr})
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "invalid scrape token", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	err := h.registry.Write(w)
+	if err != nil {
+		h.logger.Error("failed to write metrics", err)
+	}
+}
@@ -0,0 +1,288 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets, in seconds, suited to
+// HTTP requests and calls to the task source.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the Prometheus text format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: map[string]bool{},
+	}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the order they were registered.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+
+	b := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(b)
+	}
+	return b.Flush()
+}
+
+// Counter is a value which only increases, with a series for
+// each combination of label values.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounter registers a counter with the provided label names.
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labelNames: labelNames},
+		series: map[string]*counterSeries{},
+	}
+	r.register(name, c)
+	return c
+}
+
+// Inc adds one to the series with the provided label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series
+// with the provided label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.checkLabels(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := seriesKey(labelValues)
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: labelValues}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		c.writeSample(w, "", s.labelValues, nil, s.value)
+	}
+}
+
+// Histogram counts observations in buckets, with a series for
+// each combination of label values.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// NewHistogram registers a histogram with the provided upper bucket
+// bounds, in increasing order, and label names.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labelNames: labelNames},
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe records v in the series with the provided label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := seriesKey(labelValues)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			h.writeSample(w, "_bucket", s.labelValues, []string{"le", formatFloat(upper)}, float64(s.counts[i]))
+		}
+		h.writeSample(w, "_bucket", s.labelValues, []string{"le", "+Inf"}, float64(s.count))
+		h.writeSample(w, "_sum", s.labelValues, nil, s.sum)
+		h.writeSample(w, "_count", s.labelValues, nil, float64(s.count))
+	}
+}
+
+// Sample is the value of one series of a gauge.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+type gaugeFunc struct {
+	desc
+	fn func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples are provided by fn
+// each time the metrics are written.
+func (r *Registry) NewGaugeFunc(name string, help string, labelNames []string, fn func() []Sample) {
+	r.register(name, &gaugeFunc{
+		desc: desc{name: name, help: help, kind: "gauge", labelNames: labelNames},
+		fn:   fn,
+	})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	samples := g.fn()
+
+	g.writeHeader(w)
+	for _, s := range samples {
+		g.checkLabels(s.LabelValues)
+		g.writeSample(w, "", s.LabelValues, nil, s.Value)
+	}
+}
+
+type desc struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+}
+
+func (d desc) checkLabels(labelValues []string) {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", d.name, len(d.labelNames), len(labelValues)))
+	}
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.Replace(d.help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// writeSample writes one line, with extraLabel, if provided,
+// as a name and value following the metric's own labels.
+func (d desc) writeSample(w io.Writer, suffix string, labelValues []string, extraLabel []string, value float64) {
+	var labels []string
+	for i, name := range d.labelNames {
+		labels = append(labels, name+"="+quoteLabel(labelValues[i]))
+	}
+	if extraLabel != nil {
+		labels = append(labels, extraLabel[0]+"="+quoteLabel(extraLabel[1]))
+	}
+
+	if len(labels) == 0 {
+		fmt.Fprintf(w, "%s%s %s\n", d.name, suffix, formatFloat(value))
+	} else {
+		fmt.Fprintf(w, "%s%s{%s} %s\n", d.name, suffix, strings.Join(labels, ","), formatFloat(value))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*counterSeries:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*histogramSeries:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,322 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	tardylogger "github.com/robdimsdale/tardy/logger"
+)
+
+type AccessLogFormat string
+
+const (
+	// AccessLogCommon is the Common Log Format, followed by
+	// the request ID and the latency in milliseconds.
+	AccessLogCommon AccessLogFormat = "common"
+
+	// AccessLogCombined is the Combined Log Format, followed by
+	// the request ID and the latency in milliseconds.
+	AccessLogCombined AccessLogFormat = "combined"
+
+	// AccessLogJSON writes one JSON object per request,
+	// including any captured bodies.
+	AccessLogJSON AccessLogFormat = "json"
+)
+
+// AccessLogConfig controls what the access log records.
+type AccessLogConfig struct {
+	Format AccessLogFormat
+
+	// SampleRate is the fraction of requests, between 0 and 1, which are
+	// logged. Requests which fail with a server error are always logged.
+	SampleRate float64
+
+	// BodyLimit is the number of bytes of request and response bodies
+	// recorded in JSON logs. Bodies are not recorded if it is zero.
+	BodyLimit int
+}
+
+type accessLog struct {
+	logger   lager.Logger
+	redactor tardylogger.Redactor
+	config   AccessLogConfig
+
+	mu     sync.Mutex
+	w      io.Writer
+	random *rand.Rand
+}
+
+// NewAccessLog writes a line to w for every request, recording its
+// latency, status, size, user and request ID. Credentials are redacted
+// from URLs and captured bodies by redactor.
+func NewAccessLog(
+	logger lager.Logger,
+	w io.Writer,
+	redactor tardylogger.Redactor,
+	config AccessLogConfig,
+) Middleware {
+	return &accessLog{
+		logger:   logger.Session("middleware-access-log"),
+		redactor: redactor,
+		config:   config,
+		w:        w,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+type accessLogEntry struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id,omitempty"`
+	RemoteAddr   string    `json:"remote_addr"`
+	UserID       uint      `json:"user_id,omitempty"`
+	Method       string    `json:"method"`
+	URI          string    `json:"uri"`
+	Proto        string    `json:"proto"`
+	Status       int       `json:"status"`
+	Bytes        int       `json:"bytes"`
+	LatencyMS    float64   `json:"latency_ms"`
+	Referer      string    `json:"referer,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+}
+
+func (l *accessLog) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		captureBodies := l.config.Format == AccessLogJSON && l.config.BodyLimit > 0
+
+		// The URL is copied before serving, as handlers may modify it.
+		uri := l.redactor.URL(req.URL).RequestURI()
+
+		var requestBody *limitedBuffer
+		if captureBodies && req.Body != nil {
+			requestBody = &limitedBuffer{limit: l.config.BodyLimit}
+			req.Body = teeReadCloser{
+				Reader: io.TeeReader(req.Body, requestBody),
+				Closer: req.Body,
+			}
+		}
+
+		loggingResponseWriter := &responseWriter{ResponseWriter: rw}
+		if captureBodies {
+			loggingResponseWriter.body = &limitedBuffer{limit: l.config.BodyLimit}
+		}
+
+		next.ServeHTTP(loggingResponseWriter, req)
+
+		status := loggingResponseWriter.statusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if !l.sampled(status) {
+			return
+		}
+
+		entry := accessLogEntry{
+			Time:       start,
+			RemoteAddr: req.RemoteAddr,
+			Method:     req.Method,
+			URI:        uri,
+			Proto:      req.Proto,
+			Status:     status,
+			Bytes:      loggingResponseWriter.size,
+			LatencyMS:  float64(time.Since(start)) / float64(time.Millisecond),
+			Referer:    l.redactedReferer(req.Referer()),
+			UserAgent:  req.UserAgent(),
+		}
+		entry.RequestID, _ = RequestID(req)
+		entry.UserID, _ = UserID(req)
+
+		if requestBody != nil {
+			entry.RequestBody = l.redactedBody(req.Header.Get("Content-Type"), requestBody)
+		}
+		if loggingResponseWriter.body != nil {
+			entry.ResponseBody = l.redactedBody(rw.Header().Get("Content-Type"), loggingResponseWriter.body)
+		}
+
+		l.write(entry)
+	})
+}
+
+func (l *accessLog) sampled(status int) bool {
+	if status >= 500 || l.config.SampleRate >= 1 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.random.Float64() < l.config.SampleRate
+}
+
+func (l *accessLog) write(entry accessLogEntry) {
+	var line []byte
+	switch l.config.Format {
+	case AccessLogJSON:
+		b, err := json.Marshal(entry)
+		if err != nil {
+			l.logger.Error("failed to serialize access log entry", err)
+			return
+		}
+		line = append(b, '\n')
+	case AccessLogCombined:
+		line = []byte(fmt.Sprintf("%s %s %s %s\n", commonLogLine(entry), quoted(entry.Referer), quoted(entry.UserAgent), logSuffix(entry)))
+	default:
+		line = []byte(fmt.Sprintf("%s %s\n", commonLogLine(entry), logSuffix(entry)))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.w.Write(line)
+	if err != nil {
+		l.logger.Error("failed to write access log", err)
+	}
+}
+
+// commonLogLine formats the entry in the Common Log Format, with
+// the user's ID as the authenticated user.
+func commonLogLine(entry accessLogEntry) string {
+	host, _, err := net.SplitHostPort(entry.RemoteAddr)
+	if err != nil {
+		host = entry.RemoteAddr
+	}
+
+	user := "-"
+	if entry.UserID != 0 {
+		user = strconv.FormatUint(uint64(entry.UserID), 10)
+	}
+
+	return fmt.Sprintf(
+		"%s - %s [%s] %q %d %d",
+		host,
+		user,
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		entry.Method+" "+entry.URI+" "+entry.Proto,
+		entry.Status,
+		entry.Bytes,
+	)
+}
+
+// quoted quotes s for the Combined Log Format, in which missing values are "-".
+func quoted(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return strconv.Quote(s)
+}
+
+func logSuffix(entry accessLogEntry) string {
+	requestID := entry.RequestID
+	if requestID == "" {
+		requestID = "-"
+	}
+	return fmt.Sprintf("%s %.3f", requestID, entry.LatencyMS)
+}
+
+func (l *accessLog) redactedReferer(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || referer == "" {
+		return referer
+	}
+	return l.redactor.URL(u).String()
+}
+
+// redactedBody returns a captured body with credentials redacted.
+// Only JSON and form bodies, whose keys are known, are recorded.
+func (l *accessLog) redactedBody(contentType string, body *limitedBuffer) string {
+	if body.truncated {
+		return fmt.Sprintf("[body over %d bytes not recorded]", l.config.BodyLimit)
+	}
+	if body.Len() == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		err := json.Unmarshal(body.Bytes(), &v)
+		if err != nil {
+			return "[invalid JSON body not recorded]"
+		}
+		b, err := json.Marshal(l.redactor.Value(v))
+		if err != nil {
+			return "[invalid JSON body not recorded]"
+		}
+		return string(b)
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(body.String())
+		if err != nil {
+			return "[invalid form body not recorded]"
+		}
+		return l.redactor.Values(values).Encode()
+	default:
+		return fmt.Sprintf("[%s body not recorded]", mediaType)
+	}
+}
+
+// limitedBuffer keeps up to limit bytes, noting whether more were written.
+// Bodies are truncated as a whole rather than cut off part way through,
+// as a partial body cannot be parsed for redaction.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.truncated {
+		return len(p), nil
+	}
+	if b.Len()+len(p) > b.limit {
+		b.truncated = true
+		b.Reset()
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	body       *limitedBuffer
+	statusCode int
+	size       int
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	size, err := rw.ResponseWriter.Write(b)
+	if rw.body != nil {
+		rw.body.Write(b[:size])
+	}
+	rw.size += size
+
+	return size, err
+}
+
+func (rw *responseWriter) WriteHeader(s int) {
+	rw.statusCode = s
+	rw.ResponseWriter.WriteHeader(s)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pivotal-golang/lager"
+	tardylogger "github.com/robdimsdale/tardy/logger"
+)
+
+func TestAccessLogRedactsWebhookSignatures(t *testing.T) {
+	redactor := tardylogger.NewRedactor().WithSecretPath("/webhooks/wunderlist/*/{secret}")
+
+	for _, format := range []AccessLogFormat{AccessLogCommon, AccessLogCombined, AccessLogJSON} {
+		var output bytes.Buffer
+		accessLog := NewAccessLog(lager.NewLogger("test"), &output, redactor, AccessLogConfig{
+			Format:     format,
+			SampleRate: 1,
+		})
+
+		var servedPath string
+		handler := accessLog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			servedPath = r.URL.Path
+		}))
+
+		req := httptest.NewRequest("POST", "/webhooks/wunderlist/7/signature-secret", strings.NewReader("{}"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if strings.Contains(output.String(), "signature-secret") {
+			t.Errorf("expected the %s access log to redact the signature, got:\n%s", format, output.String())
+		}
+		if !strings.Contains(output.String(), "/webhooks/wunderlist/7/") {
+			t.Errorf("expected the %s access log to record the path, got:\n%s", format, output.String())
+		}
+		if servedPath != "/webhooks/wunderlist/7/signature-secret" {
+			t.Errorf("expected the request to be served unchanged, got %q", servedPath)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/context"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/tokens"
+)
+
+type contextKey int
+
+const (
+	apiTokenKey contextKey = iota
+	requestIDKey
+	userIDKey
+	accessTokenKey
+)
+
+// apiTokenScopes are the scopes required to GET each API route
+// with an API token. Other routes, including managing tokens,
+// require a logged-in session.
+var apiTokenScopes = map[string]tokens.Scope{
+	"/api/v1/tasks":         tokens.ScopeReadTasks,
+	"/api/v1/tasks/overdue": tokens.ScopeReadTasks,
+	"/api/v1/tasks/export":  tokens.ScopeExport,
+	"/api/v1/stats":         tokens.ScopeReadStats,
+}
+
+// APIToken returns the API token which authenticated the request, if any.
+func APIToken(r *http.Request) (tokens.Token, bool) {
+	t, ok := context.Get(r, apiTokenKey).(tokens.Token)
+	return t, ok
+}
+
+// UserID returns the ID of the user who authenticated the request.
+// It is set for every request allowed through by the auth middleware
+// which required authentication.
+func UserID(r *http.Request) (uint, bool) {
+	id, ok := context.Get(r, userIDKey).(uint)
+	return id, ok
+}
+
+// AccessToken returns the Wunderlist access token of the user who
+// authenticated the request, from their session or API token.
+func AccessToken(r *http.Request) (string, bool) {
+	accessToken, ok := context.Get(r, accessTokenKey).(string)
+	return accessToken, ok && accessToken != ""
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
+
+// serveAPITokenRequest authenticates the request with the API token and,
+// if it has the required scope, serves it acting with the token's
+// Wunderlist access token.
+func (s auth) serveAPITokenRequest(w http.ResponseWriter, r *http.Request, secret string, next http.Handler) {
+	token, accessToken, err := s.tokenStore.Authenticate(secret)
+	if err == tokens.ErrInvalidToken {
+		s.logger.Info("invalid api token", lager.Data{"url": r.URL.Path})
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to authenticate api token", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger := s.logger.WithData(lager.Data{"token-id": token.ID, "user-id": token.UserID, "url": r.URL.Path})
+
+	scope, ok := apiTokenScopes[r.URL.Path]
+	if !ok || r.Method != "GET" {
+		logger.Info("api token not accepted for route", lager.Data{"method": r.Method})
+		http.Error(w, "API tokens cannot be used for this request", http.StatusForbidden)
+		return
+	}
+
+	if !token.HasScope(scope) {
+		logger.Info("api token missing scope", lager.Data{"scope": scope})
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+string(scope)+`"`)
+		http.Error(w, "API token does not have scope "+string(scope), http.StatusForbidden)
+		return
+	}
+
+	context.Set(r, apiTokenKey, token)
+	context.Set(r, userIDKey, token.UserID)
+	context.Set(r, accessTokenKey, accessToken)
+
+	logger.Debug("api token accepted")
+	next.ServeHTTP(w, r)
+}
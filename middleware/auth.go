@@ -3,35 +3,54 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"strings"
 
-	"github.com/gorilla/securecookie"
+	"github.com/gorilla/context"
 	"github.com/gorilla/sessions"
 	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/tokens"
 )
 
 type auth struct {
-	logger        lager.Logger
-	cookieHandler *securecookie.SecureCookie
-	store         *sessions.CookieStore
+	logger     lager.Logger
+	store      sessions.Store
+	tokenStore tokens.Store
 }
 
+// NewAuth requires requests to have a logged-in session, or for API
+// requests, an API token in an "Authorization: Bearer" header.
 func NewAuth(
 	logger lager.Logger,
-	cookieHandler *securecookie.SecureCookie,
-	store *sessions.CookieStore,
+	store sessions.Store,
+	tokenStore tokens.Store,
 ) Middleware {
 	return auth{
-		logger:        logger.Session("middleware-auth"),
-		cookieHandler: cookieHandler,
-		store:         store,
+		logger:     logger.Session("middleware-auth"),
+		store:      store,
+		tokenStore: tokenStore,
 	}
 }
 
 func (s auth) Wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		if s.unauthenticatedAccessAllowedForURL(req.URL.Path) ||
-			s.validSession(rw, req) {
+		// Copy s so that its logger carries the request ID
+		// without affecting other requests.
+		s := s
+		s.logger = RequestLogger(req, s.logger)
+
+		// Checked first, as /metrics has its own bearer tokens.
+		if s.unauthenticatedAccessAllowedForURL(req.URL.Path) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		if secret, ok := bearerToken(req); ok {
+			s.serveAPITokenRequest(rw, req, secret, next)
+			return
+		}
+
+		if s.validSession(rw, req) {
 			next.ServeHTTP(rw, req)
 		} else {
 			s.handleUnauthenticatedRequest(rw, req)
@@ -48,12 +67,12 @@ func (s auth) handleUnauthenticatedRequest(w http.ResponseWriter, r *http.Reques
 	}
 
 	s.logger.Debug("not logged in - redirecting", lager.Data{"url": url})
-	http.Redirect(w, r, "/login", http.StatusFound)
+	http.Redirect(w, r, "/login?redirect="+neturl.QueryEscape(r.URL.RequestURI()), http.StatusFound)
 }
 
 func (s auth) unauthenticatedAccessAllowedForURL(url string) bool {
-	allowedPrefixes := []string{"/login", "/static"}
-	allowedURLs := []string{"/"}
+	allowedPrefixes := []string{"/login", "/static", "/webhooks"}
+	allowedURLs := []string{"/", "/metrics", "/healthz", "/readyz"}
 
 	for _, u := range allowedPrefixes {
 		if strings.HasPrefix(url, u) {
@@ -97,9 +116,19 @@ func (s auth) validSession(w http.ResponseWriter, r *http.Request) bool {
 		if accessToken == "" {
 			s.logger.Debug("accessToken empty in session - redirecting")
 			return false
-		} else {
-			s.logger.Debug("accessToken found in session", lager.Data{"accessToken": accessToken})
-			return true
 		}
+
+		// Sessions created before user IDs were recorded
+		// must log in again so that handlers can rely on it.
+		userID, ok := session.Values["userID"].(uint)
+		if !ok || userID == 0 {
+			s.logger.Debug("userID not found in session - redirecting")
+			return false
+		}
+
+		s.logger.Debug("accessToken found in session")
+		context.Set(r, userIDKey, userID)
+		context.Set(r, accessTokenKey, accessToken)
+		return true
 	}
 }
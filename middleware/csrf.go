@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/session"
+)
+
+const (
+	// CSRFHeader and CSRFFormField carry the session's CSRF token
+	// on state-changing requests from scripts and forms respectively.
+	CSRFHeader    = "X-CSRF-Token"
+	CSRFFormField = "csrf_token"
+)
+
+type csrf struct {
+	logger lager.Logger
+	store  sessions.Store
+}
+
+// NewCSRF rejects state-changing requests which do not carry the
+// session's CSRF token. Webhooks are exempt, as they are not sent
+// by browsers and are authenticated by their signed URLs.
+func NewCSRF(
+	logger lager.Logger,
+	store sessions.Store,
+) Middleware {
+	return csrf{
+		logger: logger.Session("middleware-csrf"),
+		store:  store,
+	}
+}
+
+func (c csrf) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		logger := RequestLogger(req, c.logger)
+
+		if !c.protected(req) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		s, err := c.store.Get(req, "session-name")
+		if err != nil {
+			logger.Error("", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token := req.Header.Get(CSRFHeader)
+		if token == "" {
+			token = req.PostFormValue(CSRFFormField)
+		}
+
+		if !session.ValidCSRFToken(s, token) {
+			logger.Info("invalid csrf token", lager.Data{"method": req.Method, "url": req.URL.Path})
+			http.Error(rw, "invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func (c csrf) protected(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return false
+	}
+
+	return !strings.HasPrefix(req.URL.Path, "/webhooks")
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/session"
+)
+
+// newCSRFSession returns the cookie of a new session and its CSRF token.
+func newCSRFSession(t *testing.T, store sessions.Store) (*http.Cookie, string) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+
+	s, err := store.Get(req, "session-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := session.CSRFToken(s)
+	err = s.Save(req, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected a session cookie, got %v", cookies)
+	}
+	return cookies[0], token
+}
+
+func TestCSRF(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("hash-key-which-is-32-bytes-long!"))
+	cookie, token := newCSRFSession(t, store)
+
+	handler := NewCSRF(lager.NewLogger("test"), store).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	form := func(token string) string {
+		return url.Values{CSRFFormField: {token}}.Encode()
+	}
+
+	for _, test := range []struct {
+		description string
+		method      string
+		path        string
+		header      string
+		form        string
+		noCookie    bool
+		status      int
+	}{
+		{description: "safe methods", method: "GET", path: "/api/v1/tasks", status: http.StatusNoContent},
+		{description: "no token", method: "POST", path: "/logout", status: http.StatusForbidden},
+		{description: "header token", method: "PUT", path: "/api/v1/settings", header: token, status: http.StatusNoContent},
+		{description: "form token", method: "POST", path: "/logout", form: form(token), status: http.StatusNoContent},
+		{description: "wrong header token", method: "DELETE", path: "/api/v1/tokens/1", header: token + "x", status: http.StatusForbidden},
+		{description: "wrong form token", method: "POST", path: "/logout", form: form("x"), status: http.StatusForbidden},
+		{description: "token without its session", method: "POST", path: "/logout", header: token, noCookie: true, status: http.StatusForbidden},
+		{description: "webhooks", method: "POST", path: "/webhooks/wunderlist/1/signature", noCookie: true, status: http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.form))
+		if test.form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if test.header != "" {
+			req.Header.Set(CSRFHeader, test.header)
+		}
+		if !test.noCookie {
+			req.AddCookie(cookie)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: expected %d, got %d", test.description, test.status, rec.Code)
+		}
+	}
+}
+
+func TestCSRFTokenIsKeptUntilRegenerated(t *testing.T) {
+	s := sessions.NewSession(sessions.NewCookieStore([]byte("hash-key-which-is-32-bytes-long!")), "session-name")
+
+	token, created := session.CSRFToken(s)
+	if !created || token == "" {
+		t.Fatalf("expected a new token, got %q", token)
+	}
+
+	again, created := session.CSRFToken(s)
+	if created || again != token {
+		t.Errorf("expected the session's token to be kept, got %q", again)
+	}
+
+	if session.ValidCSRFToken(s, "") || session.ValidCSRFToken(sessions.NewSession(nil, "session-name"), token) {
+		t.Errorf("expected empty tokens and sessions without tokens to be rejected")
+	}
+}
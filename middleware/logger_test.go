@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	tardylogger "github.com/robdimsdale/tardy/logger"
+)
+
+func TestLoggedRequestRedactsCredentials(t *testing.T) {
+	req := httptest.NewRequest("GET", "/login/callback?code=oauth-code&state=oauth-state&redirect=%2Fstats", nil)
+	req.Header.Set("Authorization", "Bearer api-token-secret")
+	req.Header.Set("Cookie", "session-name=session-cookie-value")
+	req.Header.Set("Accept", "text/html")
+	req.Form = url.Values{"code": {"oauth-code"}, "list": {"7"}}
+	req.PostForm = url.Values{"client_secret": {"client-secret-value"}}
+
+	originalURL := req.URL.String()
+	originalRequestURI := req.RequestURI
+	originalHeader := cloneHeader(req.Header)
+	originalForm := cloneValues(req.Form)
+	originalPostForm := cloneValues(req.PostForm)
+
+	logged := loggedRequest(req, tardylogger.NewRedactor())
+
+	output, err := json.Marshal(logged)
+	if err != nil {
+		t.Fatalf("failed to serialize logged request: %s", err)
+	}
+	for _, secret := range []string{
+		"oauth-code",
+		"oauth-state",
+		"api-token-secret",
+		"session-cookie-value",
+		"client-secret-value",
+	} {
+		if strings.Contains(string(output), secret) {
+			t.Errorf("expected %q to be redacted, got:\n%s", secret, output)
+		}
+	}
+	if logged.URL.Query().Get("redirect") != "/stats" || logged.Header.Get("Accept") != "text/html" {
+		t.Errorf("expected values which are not credentials to be kept, got:\n%s", output)
+	}
+	if logged.Form.Get("list") != "7" {
+		t.Errorf("expected form values which are not credentials to be kept, got %v", logged.Form)
+	}
+
+	if req.URL.String() != originalURL || req.RequestURI != originalRequestURI {
+		t.Errorf("expected the request URL to be unchanged, got %s", req.URL)
+	}
+	if !reflect.DeepEqual(req.Header, originalHeader) {
+		t.Errorf("expected the request headers to be unchanged, got %v", req.Header)
+	}
+	if !reflect.DeepEqual(req.Form, originalForm) || !reflect.DeepEqual(req.PostForm, originalPostForm) {
+		t.Errorf("expected the request forms to be unchanged, got %v and %v", req.Form, req.PostForm)
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for k, v := range h {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+func cloneValues(v url.Values) url.Values {
+	return url.Values(cloneHeader(http.Header(v)))
+}
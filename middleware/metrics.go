@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robdimsdale/tardy/metrics"
+)
+
+// unroutedRoute labels requests which were not served by a named
+// route, such as those rejected by earlier middleware or not found.
+const unroutedRoute = "unrouted"
+
+type requestMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// NewMetrics counts requests and records their latency, labelled by
+// the name of the mux route which served them. Routes are named by
+// their path templates so that the number of series stays bounded.
+func NewMetrics(registry *metrics.Registry) Middleware {
+	return requestMetrics{
+		requests: registry.NewCounter(
+			"tardy_http_requests_total",
+			"HTTP requests served, by route, method and status code.",
+			"route", "method", "status",
+		),
+		duration: registry.NewHistogram(
+			"tardy_http_request_duration_seconds",
+			"Time taken to serve HTTP requests, by route and method.",
+			metrics.DefaultBuckets,
+			"route", "method",
+		),
+	}
+}
+
+func (m requestMetrics) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		metricsResponseWriter := &responseWriter{ResponseWriter: rw}
+		next.ServeHTTP(metricsResponseWriter, req)
+
+		status := metricsResponseWriter.statusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := unroutedRoute
+		if r := mux.CurrentRoute(req); r != nil && r.GetName() != "" {
+			route = r.GetName()
+		}
+
+		m.requests.Inc(route, req.Method, strconv.Itoa(status))
+		m.duration.Observe(time.Since(start).Seconds(), route, req.Method)
+	})
+}
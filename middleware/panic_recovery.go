@@ -1,30 +1,177 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
 	"net/http"
+	"runtime/debug"
+	"strings"
 
 	"github.com/pivotal-golang/lager"
+	tardylogger "github.com/robdimsdale/tardy/logger"
 )
 
+// PanicReport describes a panic recovered while serving a request.
+type PanicReport struct {
+	RequestID string
+	// UserID is zero if the request was not authenticated.
+	UserID uint
+	Method string
+	// URL has credentials redacted.
+	URL   string
+	Err   error
+	Stack []byte
+}
+
+// ErrorReporter sends recovered panics somewhere they will be noticed,
+// such as an error tracking service. ReportPanic is called while the
+// error response is being served, so it should not block.
+type ErrorReporter interface {
+	ReportPanic(report PanicReport)
+}
+
 type panicRecovery struct {
-	logger lager.Logger
+	logger    lager.Logger
+	redactor  tardylogger.Redactor
+	templates *template.Template
+	reporter  ErrorReporter
 }
 
-func NewPanicRecovery(logger lager.Logger) Middleware {
-	return &panicRecovery{logger}
+// NewPanicRecovery logs panics in later handlers with their stack
+// and, if reporter is not nil, reports them. API requests get a JSON
+// error and other requests the "error" page from templates, both
+// including the request ID so users can quote it.
+func NewPanicRecovery(
+	logger lager.Logger,
+	redactor tardylogger.Redactor,
+	templates *template.Template,
+	reporter ErrorReporter,
+) Middleware {
+	return &panicRecovery{
+		logger:    logger.Session("middleware-panic-recovery"),
+		redactor:  redactor,
+		templates: templates,
+		reporter:  reporter,
+	}
 }
 
 func (p panicRecovery) Wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		recoveryResponseWriter := &responseWriter{ResponseWriter: rw}
+
 		defer func() {
-			if panicInfo := recover(); panicInfo != nil {
-				rw.WriteHeader(http.StatusInternalServerError)
-				p.logger.Error("Panic while serving request", nil, lager.Data{
-					"request":   loggedRequest(*req),
-					"panicInfo": panicInfo,
-				})
+			panicInfo := recover()
+			if panicInfo == nil {
+				return
+			}
+
+			// Handlers abort deliberately with ErrAbortHandler,
+			// which the server handles without logging.
+			if panicInfo == http.ErrAbortHandler {
+				panic(panicInfo)
 			}
+
+			p.recovered(recoveryResponseWriter, req, panicInfo, debug.Stack())
 		}()
-		next.ServeHTTP(rw, req)
+
+		next.ServeHTTP(recoveryResponseWriter, req)
+	})
+}
+
+func (p panicRecovery) recovered(rw *responseWriter, req *http.Request, panicInfo interface{}, stack []byte) {
+	err, ok := panicInfo.(error)
+	if !ok {
+		err = fmt.Errorf("%v", panicInfo)
+	}
+
+	requestID, _ := RequestID(req)
+	userID, _ := UserID(req)
+	started := rw.statusCode != 0
+
+	RequestLogger(req, p.logger).Error("panic while serving request", err, lager.Data{
+		"request":          loggedRequest(req, p.redactor),
+		"stack":            string(stack),
+		"response-started": started,
 	})
+
+	if p.reporter != nil {
+		p.reporter.ReportPanic(PanicReport{
+			RequestID: requestID,
+			UserID:    userID,
+			Method:    req.Method,
+			URL:       p.redactor.URL(req.URL).String(),
+			Err:       err,
+			Stack:     stack,
+		})
+	}
+
+	// The status has already been sent, so the response cannot be
+	// replaced. Aborting closes the connection, or resets the HTTP/2
+	// stream, so the client does not take a partial response as complete.
+	if started {
+		panic(http.ErrAbortHandler)
+	}
+
+	// Headers the handler set for its own response do not
+	// apply to the error response.
+	header := rw.Header()
+	for _, h := range []string{
+		"Content-Disposition",
+		"Content-Encoding",
+		"Content-Length",
+		"ETag",
+		"Last-Modified",
+	} {
+		header.Del(h)
+	}
+	header.Set("Cache-Control", "no-store")
+
+	if strings.HasPrefix(req.URL.Path, "/api") {
+		p.writeJSONError(rw, requestID)
+	} else {
+		p.writeErrorPage(rw, requestID)
+	}
+}
+
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func (p panicRecovery) writeJSONError(rw http.ResponseWriter, requestID string) {
+	body, err := json.Marshal(errorResponse{
+		Error:     http.StatusText(http.StatusInternalServerError),
+		RequestID: requestID,
+	})
+	if err != nil {
+		p.logger.Error("failed to serialize error response", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusInternalServerError)
+	rw.Write(append(body, '\n'))
+}
+
+func (p panicRecovery) writeErrorPage(rw http.ResponseWriter, requestID string) {
+	// The page is rendered into a buffer so that a failure
+	// can still be served as a plain error.
+	var page bytes.Buffer
+	err := p.templates.ExecuteTemplate(&page, "error", struct {
+		RequestID string
+	}{
+		RequestID: requestID,
+	})
+	if err != nil {
+		p.logger.Error("failed to render error page", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.WriteHeader(http.StatusInternalServerError)
+	page.WriteTo(rw)
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/securecookie"
+	"github.com/pivotal-golang/lager"
+)
+
+// RequestIDHeader carries the request ID on requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the size of request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type requestID struct {
+	logger lager.Logger
+}
+
+// NewRequestID gives every request an ID, taken from the X-Request-ID
+// header if the client provided a valid one or otherwise generated.
+// The ID is echoed in the response and added to request loggers;
+// see RequestLogger.
+func NewRequestID(logger lager.Logger) Middleware {
+	return requestID{
+		logger: logger.Session("middleware-request-id"),
+	}
+}
+
+func (m requestID) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			if id != "" {
+				m.logger.Info("ignoring invalid request id", lager.Data{"url": req.URL.Path})
+			}
+			id = generateRequestID()
+		}
+
+		context.Set(req, requestIDKey, id)
+		rw.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// RequestID returns the ID of the request, if it has one.
+func RequestID(r *http.Request) (string, bool) {
+	id, ok := context.Get(r, requestIDKey).(string)
+	return id, ok
+}
+
+// RequestLogger returns logger with the request's ID added to its data,
+// so that it and every session created from it can be correlated with
+// the request. If the request has no ID, logger is returned unchanged.
+func RequestLogger(r *http.Request, logger lager.Logger) lager.Logger {
+	id, ok := RequestID(r)
+	if !ok {
+		return logger
+	}
+	return logger.WithData(lager.Data{"request-id": id})
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII characters,
+// so that clients cannot inject anything awkward into logs or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func generateRequestID() string {
+	return hex.EncodeToString(securecookie.GenerateRandomKey(16))
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+)
+
+// TaskSource returns a task source acting as the user who authenticated
+// the request, and the user's ID. If the request was not authenticated
+// an error is written to w and false returned.
+func TaskSource(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger lager.Logger,
+	factory tardy.TaskSourceFactory,
+) (tardy.TaskSource, uint, bool) {
+	accessToken, ok := AccessToken(r)
+	if !ok {
+		err := fmt.Errorf("accessToken not found in request")
+		logger.Error("", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, 0, false
+	}
+
+	userID, ok := UserID(r)
+	if !ok {
+		err := fmt.Errorf("user not found in request")
+		logger.Error("", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, 0, false
+	}
+
+	return factory.NewTaskSource(logger, accessToken), userID, true
+}
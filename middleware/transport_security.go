@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// TransportSecurityConfig controls how requests are kept on HTTPS.
+type TransportSecurityConfig struct {
+	// RedirectToHTTPS redirects plain HTTP requests to HTTPS.
+	RedirectToHTTPS bool
+
+	// HTTPSPort is the port redirects are sent to when tardy serves
+	// HTTPS itself. If zero, the port of the request is kept.
+	HTTPSPort int
+
+	// ExemptLocalhost serves plain HTTP requests for localhost,
+	// for local development.
+	ExemptLocalhost bool
+
+	// ExemptPaths are served over plain HTTP, for health checks
+	// which do not follow redirects.
+	ExemptPaths []string
+
+	// TrustedProxies are trusted to report the original scheme in
+	// Forwarded or X-Forwarded-Proto headers.
+	TrustedProxies TrustedProxies
+
+	// HSTSMaxAge is sent in a Strict-Transport-Security header on HTTPS
+	// responses, unless it is zero.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+}
+
+type transportSecurity struct {
+	logger lager.Logger
+	config TransportSecurityConfig
+	hsts   string
+}
+
+// NewTransportSecurity redirects plain HTTP requests to HTTPS and tells
+// browsers to only use HTTPS in future. GET and HEAD requests are
+// redirected permanently with 301; other methods with 308 so that their
+// method and body are preserved.
+func NewTransportSecurity(logger lager.Logger, config TransportSecurityConfig) Middleware {
+	var hsts string
+	if config.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int64(config.HSTSMaxAge/time.Second))
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return transportSecurity{
+		logger: logger.Session("middleware-transport-security"),
+		config: config,
+		hsts:   hsts,
+	}
+}
+
+func (t transportSecurity) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		logger := RequestLogger(req, t.logger)
+
+		if t.secure(req) {
+			if t.hsts != "" {
+				rw.Header().Set("Strict-Transport-Security", t.hsts)
+			}
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		if !t.config.RedirectToHTTPS || (t.config.ExemptLocalhost && isLocalhost(req.Host)) || t.exemptPath(req.URL.Path) {
+			logger.Debug("serving plain http request", lager.Data{"url": req.URL.Path})
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		status := http.StatusPermanentRedirect
+		if req.Method == "GET" || req.Method == "HEAD" {
+			status = http.StatusMovedPermanently
+		}
+
+		target := "https://" + t.httpsHost(req.Host) + req.URL.RequestURI()
+		logger.Debug("redirecting to https", lager.Data{"url": req.URL.Path, "status": status})
+		http.Redirect(rw, req, target, status)
+	})
+}
+
+func (t transportSecurity) exemptPath(path string) bool {
+	for _, p := range t.config.ExemptPaths {
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// secure reports whether the request reached tardy, or the trusted
+// proxy in front of it, over HTTPS.
+func (t transportSecurity) secure(req *http.Request) bool {
+	if req.TLS != nil {
+		return true
+	}
+
+	if !t.config.TrustedProxies.Contains(req.RemoteAddr) {
+		return false
+	}
+
+	// Proxies append to these headers, so only the last value was set by
+	// the trusted proxy; earlier values may have been sent by the client.
+	if proto, ok := forwardedProto(lastHeaderValue(req.Header, "Forwarded")); ok {
+		return strings.EqualFold(proto, "https")
+	}
+
+	proto := lastHeaderValue(req.Header, "X-Forwarded-Proto")
+	return strings.EqualFold(proto, "https")
+}
+
+// lastHeaderValue returns the last element of the comma-separated
+// values of every header with the provided name.
+func lastHeaderValue(header http.Header, name string) string {
+	values := header[http.CanonicalHeaderKey(name)]
+	if len(values) == 0 {
+		return ""
+	}
+
+	elements := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(elements[len(elements)-1])
+}
+
+func (t transportSecurity) httpsHost(host string) string {
+	if t.config.HTTPSPort == 0 {
+		return host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if t.config.HTTPSPort == 443 {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(t.config.HTTPSPort))
+}
+
+// forwardedProto returns the proto parameter of an element
+// of an RFC 7239 Forwarded header.
+func forwardedProto(element string) (string, bool) {
+	if element == "" {
+		return "", false
+	}
+
+	for _, pair := range strings.Split(element, ";") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(kv[0], "proto") {
+			return strings.Trim(kv[1], `"`), true
+		}
+	}
+	return "", false
+}
+
+func isLocalhost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// TrustedProxies is a set of addresses trusted to report
+// the scheme used by the client.
+type TrustedProxies struct {
+	all  bool
+	nets []*net.IPNet
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR ranges. "*" trusts every address, for platforms whose routers do
+// not have fixed addresses; the empty string trusts none.
+func ParseTrustedProxies(s string) (TrustedProxies, error) {
+	var p TrustedProxies
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			p.all = true
+			continue
+		case !strings.Contains(entry, "/"):
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return TrustedProxies{}, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			entry = fmt.Sprintf("%s/%d", ip, bits)
+		}
+
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return TrustedProxies{}, fmt.Errorf("invalid CIDR range %q", entry)
+		}
+		p.nets = append(p.nets, n)
+	}
+	return p, nil
+}
+
+// Empty reports whether no address is trusted.
+func (p TrustedProxies) Empty() bool {
+	return !p.all && len(p.nets) == 0
+}
+
+// Contains reports whether the address, an IP with an optional port,
+// is trusted.
+func (p TrustedProxies) Contains(addr string) bool {
+	if p.all {
+		return true
+	}
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.1, 192.168.0.0/16,2001:db8::/32, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, trusted := range map[string]bool{
+		"10.0.0.1":           true,
+		"10.0.0.1:1234":      true,
+		"10.0.0.2":           false,
+		"192.168.4.5:80":     true,
+		"192.169.0.1":        false,
+		"[2001:db8::1]:443":  true,
+		"2001:db9::1":        false,
+		"[::1]:8080":         true,
+		"::ffff:10.0.0.1":    true,
+		"not an address":     false,
+		"":                   false,
+		"10.0.0.1:1234:5678": false,
+	} {
+		if proxies.Contains(addr) != trusted {
+			t.Errorf("expected %q to be trusted: %t", addr, trusted)
+		}
+	}
+
+	if proxies.Empty() {
+		t.Errorf("expected proxies not to be empty")
+	}
+
+	none, err := ParseTrustedProxies("")
+	if err != nil || !none.Empty() || none.Contains("127.0.0.1") {
+		t.Errorf("expected no proxies to be trusted by default, got %+v, %v", none, err)
+	}
+
+	all, err := ParseTrustedProxies("*")
+	if err != nil || all.Empty() || !all.Contains("203.0.113.9:5555") {
+		t.Errorf("expected * to trust every address, got %+v, %v", all, err)
+	}
+
+	for _, s := range []string{"10.0.0.256", "10.0.0.0/33", "proxy.example.com"} {
+		_, err := ParseTrustedProxies(s)
+		if err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestTransportSecurity(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewTransportSecurity(lager.NewLogger("test"), TransportSecurityConfig{
+		RedirectToHTTPS: true,
+		ExemptLocalhost: true,
+		ExemptPaths:     []string{"/healthz"},
+		TrustedProxies:  proxies,
+		HSTSMaxAge:      time.Hour,
+	}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	const proxy = "10.0.0.1:4567"
+	const client = "203.0.113.9:4567"
+
+	for _, test := range []struct {
+		description string
+		method      string
+		host        string
+		path        string
+		remoteAddr  string
+		tls         bool
+		headers     map[string][]string
+		status      int
+	}{
+		{description: "native tls", remoteAddr: client, tls: true, status: http.StatusNoContent},
+		{description: "plain http", remoteAddr: client, status: http.StatusMovedPermanently},
+		{description: "plain http post", method: "POST", remoteAddr: client, status: http.StatusPermanentRedirect},
+		{description: "localhost", host: "localhost:8080", remoteAddr: client, status: http.StatusNoContent},
+		{description: "exempt path", path: "/healthz", remoteAddr: client, status: http.StatusNoContent},
+		{description: "trusted proxy", remoteAddr: proxy, headers: map[string][]string{"X-Forwarded-Proto": {"https"}}, status: http.StatusNoContent},
+		{description: "untrusted client", remoteAddr: client, headers: map[string][]string{"X-Forwarded-Proto": {"https"}}, status: http.StatusMovedPermanently},
+		{description: "proxy appended http", remoteAddr: proxy, headers: map[string][]string{"X-Forwarded-Proto": {"https, http"}}, status: http.StatusMovedPermanently},
+		{description: "proxy appended https", remoteAddr: proxy, headers: map[string][]string{"X-Forwarded-Proto": {"http, https"}}, status: http.StatusNoContent},
+		{description: "proxy added a header line", remoteAddr: proxy, headers: map[string][]string{"X-Forwarded-Proto": {"https", "http"}}, status: http.StatusMovedPermanently},
+		{description: "forwarded", remoteAddr: proxy, headers: map[string][]string{"Forwarded": {`for=203.0.113.9;proto="https"`}}, status: http.StatusNoContent},
+		{description: "forwarded appended http", remoteAddr: proxy, headers: map[string][]string{"Forwarded": {"proto=https, for=203.0.113.9;Proto=http"}}, status: http.StatusMovedPermanently},
+		{description: "forwarded takes precedence", remoteAddr: proxy, headers: map[string][]string{"Forwarded": {"proto=http"}, "X-Forwarded-Proto": {"https"}}, status: http.StatusMovedPermanently},
+		{description: "forwarded without proto", remoteAddr: proxy, headers: map[string][]string{"Forwarded": {"for=203.0.113.9"}, "X-Forwarded-Proto": {"https"}}, status: http.StatusNoContent},
+	} {
+		method := test.method
+		if method == "" {
+			method = "GET"
+		}
+		path := test.path
+		if path == "" {
+			path = "/stats?from=2016-01-01"
+		}
+
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.host != "" {
+			req.Host = test.host
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		} else {
+			req.TLS = nil
+		}
+		for name, values := range test.headers {
+			req.Header[name] = values
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s: expected %d, got %d", test.description, test.status, rec.Code)
+			continue
+		}
+
+		hsts := rec.Header().Get("Strict-Transport-Security")
+		secure := test.status == http.StatusNoContent && test.host == "" && test.path == ""
+		if secure && hsts != "max-age=3600" {
+			t.Errorf("%s: expected HSTS on an https response, got %q", test.description, hsts)
+		}
+		if !secure && hsts != "" {
+			t.Errorf("%s: expected no HSTS on a plain http response, got %q", test.description, hsts)
+		}
+
+		if test.status != http.StatusNoContent {
+			location := rec.Header().Get("Location")
+			if location != "https://example.com/stats?from=2016-01-01" {
+				t.Errorf("%s: expected a redirect to the same URL over https, got %q", test.description, location)
+			}
+		}
+	}
+}
+
+func TestTransportSecurityRedirectsToHTTPSPort(t *testing.T) {
+	for _, test := range []struct {
+		port     int
+		host     string
+		expected string
+	}{
+		{0, "example.com:8080", "https://example.com:8080/"},
+		{443, "example.com:8080", "https://example.com/"},
+		{8443, "example.com:8080", "https://example.com:8443/"},
+		{8443, "example.com", "https://example.com:8443/"},
+		{443, "[::1]:8080", "https://[::1]/"},
+		{8443, "[::1]:8080", "https://[::1]:8443/"},
+	} {
+		handler := NewTransportSecurity(lager.NewLogger("test"), TransportSecurityConfig{
+			RedirectToHTTPS: true,
+			HTTPSPort:       test.port,
+		}).Wrap(http.NotFoundHandler())
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = test.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if location := rec.Header().Get("Location"); location != test.expected {
+			t.Errorf("port %d and host %s: expected a redirect to %s, got %s", test.port, test.host, test.expected, location)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const csrfTokenKey = "csrfToken"
+
+// CSRFToken returns the session's CSRF token, creating one if the session
+// does not yet have one. It reports whether the token was created,
+// in which case the session must be saved.
+func CSRFToken(s *sessions.Session) (string, bool) {
+	if token, ok := s.Values[csrfTokenKey].(string); ok && token != "" {
+		return token, false
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	s.Values[csrfTokenKey] = token
+	return token, true
+}
+
+// ValidCSRFToken reports whether token matches the session's CSRF token.
+func ValidCSRFToken(s *sessions.Session, token string) bool {
+	expected, ok := s.Values[csrfTokenKey].(string)
+	if !ok || expected == "" || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
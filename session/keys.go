@@ -0,0 +1,78 @@
+package session
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+)
+
+// KeyPair authenticates (HashKey) and encrypts (BlockKey) session data.
+type KeyPair struct {
+	HashKey  []byte
+	BlockKey []byte
+}
+
+// GenerateKeyPair returns a random KeyPair.
+func GenerateKeyPair() KeyPair {
+	return KeyPair{
+		HashKey:  securecookie.GenerateRandomKey(64),
+		BlockKey: securecookie.GenerateRandomKey(32),
+	}
+}
+
+// ParseKeys parses a comma-separated list of key pairs, each of the form
+// <hash key>:<block key> with both keys base64-encoded.
+// The first pair is used to encode new sessions; the others are only used
+// to decode existing sessions, so that keys can be rotated by adding a new
+// pair to the front of the list and removing the oldest once its sessions
+// have expired. API tokens are also encrypted with the pairs, and are
+// re-encrypted with the first when tardy starts, so they survive the
+// removal provided tardy has been restarted with the new pair first.
+func ParseKeys(s string) ([]KeyPair, error) {
+	var keys []KeyPair
+
+	for i, pair := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("session key pair %d must be of the form <hash key>:<block key>", i+1)
+		}
+
+		hashKey, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("session hash key %d must be base64-encoded: %s", i+1, err.Error())
+		}
+		if len(hashKey) < 32 {
+			return nil, fmt.Errorf("session hash key %d must be at least 32 bytes, got %d", i+1, len(hashKey))
+		}
+
+		blockKey, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("session block key %d must be base64-encoded: %s", i+1, err.Error())
+		}
+		switch len(blockKey) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("session block key %d must be 16, 24 or 32 bytes, got %d", i+1, len(blockKey))
+		}
+
+		keys = append(keys, KeyPair{HashKey: hashKey, BlockKey: blockKey})
+	}
+
+	return keys, nil
+}
+
+// Codecs returns the securecookie codecs for the keys, in order,
+// for use with securecookie.EncodeMulti and DecodeMulti.
+func Codecs(keys []KeyPair) []securecookie.Codec {
+	return securecookie.CodecsFromPairs(keyPairs(keys)...)
+}
+
+func keyPairs(keys []KeyPair) [][]byte {
+	pairs := [][]byte{}
+	for _, k := range keys {
+		pairs = append(pairs, k.HashKey, k.BlockKey)
+	}
+	return pairs
+}
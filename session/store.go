@@ -0,0 +1,220 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	// KindFile keeps session data on the server, encrypted, with only
+	// the session ID in the browser's cookie.
+	KindFile = "file"
+
+	// KindCookie keeps the encrypted session data in the browser's cookie.
+	KindCookie = "cookie"
+)
+
+// NewStore returns a sessions.Store of the provided kind whose sessions
+// expire after maxAge seconds. If secure is true, session cookies are
+// only sent over HTTPS. Sessions which cannot be read, for example
+// because they were encoded with a key which has since been removed,
+// are replaced by new, empty sessions rather than causing an error.
+func NewStore(logger lager.Logger, kind string, dir string, maxAge int, secure bool, keys []KeyPair) (sessions.Store, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one session key pair must be provided")
+	}
+
+	var s sessions.Store
+	switch kind {
+	case KindFile:
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return nil, err
+		}
+
+		fs := sessions.NewFilesystemStore(dir, keyPairs(keys)...)
+		fs.Options.HttpOnly = true
+		fs.Options.Secure = secure
+		fs.MaxAge(maxAge)
+		s = fs
+	case KindCookie:
+		cs := sessions.NewCookieStore(keyPairs(keys)...)
+		cs.Options.HttpOnly = true
+		cs.Options.Secure = secure
+		cs.MaxAge(maxAge)
+		s = cs
+	default:
+		return nil, fmt.Errorf("session store must be %s or %s, got %q", KindFile, KindCookie, kind)
+	}
+
+	return &store{
+		logger: logger.Session("session-store"),
+		store:  s,
+		kind:   kind,
+		dir:    dir,
+	}, nil
+}
+
+// Regenerate destroys the request's session and returns a new, empty
+// session with a new ID to replace it, which must be saved. Sessions are
+// regenerated when a user logs in so that a session ID or CSRF token
+// obtained before login cannot be used to act as the user.
+func Regenerate(store sessions.Store, r *http.Request, w http.ResponseWriter, name string) (*sessions.Session, error) {
+	old, err := store.Get(r, name)
+	if err != nil {
+		return nil, err
+	}
+	options := *old.Options
+
+	if !old.IsNew {
+		old.Options.MaxAge = -1
+		err := old.Save(r, w)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := sessions.NewSession(store, name)
+	s.Options = &options
+	s.IsNew = true
+	return s, nil
+}
+
+// CountActive returns the number of unexpired sessions held by a file
+// store in dir. Sessions held in cookies cannot be counted.
+func CountActive(dir string, maxAge time.Duration) (int, error) {
+	files, err := sessionFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, f := range files {
+		if time.Since(f.modTime) < maxAge {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// PruneExpired deletes the sessions held by a file store in dir which
+// have not been saved for maxAge, and so can no longer be decoded,
+// returning how many were deleted.
+func PruneExpired(dir string, maxAge time.Duration) (int, error) {
+	files, err := sessionFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, f := range files {
+		if time.Since(f.modTime) < maxAge {
+			continue
+		}
+
+		err := os.Remove(f.name)
+		if err != nil && !os.IsNotExist(err) {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// WatchExpired prunes expired sessions from a file store in dir now
+// and then every interval, so that the directory does not grow forever.
+func WatchExpired(logger lager.Logger, dir string, maxAge time.Duration, interval time.Duration) {
+	logger = logger.Session("session-pruner", lager.Data{"dir": dir})
+
+	prune := func() {
+		pruned, err := PruneExpired(dir, maxAge)
+		if err != nil {
+			logger.Error("failed-to-prune-expired-sessions", err, lager.Data{"pruned": pruned})
+			return
+		}
+		logger.Debug("pruned-expired-sessions", lager.Data{"pruned": pruned})
+	}
+
+	prune()
+	go func() {
+		for range time.Tick(interval) {
+			prune()
+		}
+	}()
+}
+
+type sessionFile struct {
+	name    string
+	modTime time.Time
+}
+
+// sessionFiles returns the files of the sessions held by a file store in
+// dir. Files deleted while they are listed are skipped.
+func sessionFiles(dir string) ([]sessionFile, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "session_*"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := []sessionFile{}
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		files = append(files, sessionFile{name: name, modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+type store struct {
+	logger lager.Logger
+	store  sessions.Store
+	kind   string
+	dir    string
+}
+
+func (s *store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	// The registry caches the session for the rest of the request,
+	// and calls s.New if it is not already cached.
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session, err := s.store.New(r, name)
+	if err != nil {
+		s.logger.Info("discarding-unreadable-session", lager.Data{"name": name, "error": err.Error()})
+
+		session.ID = ""
+		session.Values = map[interface{}]interface{}{}
+		session.IsNew = true
+	}
+
+	return session, nil
+}
+
+// Save saves the session, or destroys it if its MaxAge is negative.
+func (s *store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge >= 0 {
+		return s.store.Save(r, w, session)
+	}
+
+	// The filesystem store would otherwise keep the destroyed
+	// session's data until its file is deleted by hand.
+	if s.kind == KindFile && session.ID != "" {
+		err := os.Remove(filepath.Join(s.dir, "session_"+session.ID))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	session.Values = map[interface{}]interface{}{}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+	return nil
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tardy-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for name, modTime := range map[string]time.Time{
+		"session_active":  now.Add(-time.Hour),
+		"session_expired": now.Add(-25 * time.Hour),
+		"other_file":      now.Add(-25 * time.Hour),
+	} {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte("data"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := CountActive(dir, 24*time.Hour)
+	if err != nil || count != 1 {
+		t.Errorf("expected 1 active session, got %d, %v", count, err)
+	}
+
+	pruned, err := PruneExpired(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to prune sessions: %s", err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected 1 session to be pruned, got %d", pruned)
+	}
+
+	for name, exists := range map[string]bool{
+		"session_active":  true,
+		"session_expired": false,
+		"other_file":      true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists != (err == nil) {
+			t.Errorf("expected %s to exist: %t, got %v", name, exists, err)
+		}
+	}
+
+	count, err = CountActive(dir, 24*time.Hour)
+	if err != nil || count != 1 {
+		t.Errorf("expected pruning to keep the active session, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,76 @@
+package tardy
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats summarises the lateness of a set of tasks.
+// Lateness is measured in days; negative values are early completions.
+type Stats struct {
+	Count       int `json:"count"`
+	LateCount   int `json:"late_count"`
+	OnTimeCount int `json:"on_time_count"`
+	EarlyCount  int `json:"early_count"`
+
+	MeanDays   float64 `json:"mean_days"`
+	MedianDays float64 `json:"median_days"`
+	P90Days    float64 `json:"p90_days"`
+	MaxDays    int     `json:"max_days"`
+
+	// OnTimePercent includes tasks completed early.
+	OnTimePercent float64 `json:"on_time_percent"`
+	EarlyPercent  float64 `json:"early_percent"`
+}
+
+// NewStats computes Stats for the provided tasks.
+// The zero Stats is returned if there are no tasks.
+func NewStats(tasks []Task) Stats {
+	if len(tasks) == 0 {
+		return Stats{}
+	}
+
+	days := make([]int, len(tasks))
+	for i, t := range tasks {
+		days[i] = t.Days
+	}
+	sort.Ints(days)
+
+	s := Stats{
+		Count:   len(days),
+		MaxDays: days[len(days)-1],
+	}
+
+	total := 0
+	for _, d := range days {
+		total += d
+		switch {
+		case d > 0:
+			s.LateCount++
+		case d < 0:
+			s.EarlyCount++
+			s.OnTimeCount++
+		default:
+			s.OnTimeCount++
+		}
+	}
+
+	s.MeanDays = float64(total) / float64(s.Count)
+	s.MedianDays = percentile(days, 50)
+	s.P90Days = percentile(days, 90)
+	s.OnTimePercent = 100 * float64(s.OnTimeCount) / float64(s.Count)
+	s.EarlyPercent = 100 * float64(s.EarlyCount) / float64(s.Count)
+
+	return s
+}
+
+// percentile linearly interpolates between the closest ranks
+// of the sorted, non-empty values.
+func percentile(sorted []int, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	weight := rank - float64(lower)
+	return float64(sorted[lower])*(1-weight) + float64(sorted[upper])*weight
+}
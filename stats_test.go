@@ -0,0 +1,75 @@
+package tardy
+
+import (
+	"math"
+	"testing"
+)
+
+func tasksWithDays(days ...int) []Task {
+	tasks := []Task{}
+	for _, d := range days {
+		tasks = append(tasks, Task{Days: d})
+	}
+	return tasks
+}
+
+func TestNewStats(t *testing.T) {
+	s := NewStats(tasksWithDays(5, 0, -2, 1, 0))
+
+	expected := Stats{
+		Count:         5,
+		LateCount:     2,
+		OnTimeCount:   3,
+		EarlyCount:    1,
+		MeanDays:      0.8,
+		MedianDays:    0,
+		P90Days:       3.4,
+		MaxDays:       5,
+		OnTimePercent: 60,
+		EarlyPercent:  20,
+	}
+
+	if s.Count != expected.Count || s.LateCount != expected.LateCount ||
+		s.OnTimeCount != expected.OnTimeCount || s.EarlyCount != expected.EarlyCount ||
+		s.MaxDays != expected.MaxDays {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+
+	for _, f := range []struct {
+		name     string
+		actual   float64
+		expected float64
+	}{
+		{"mean", s.MeanDays, expected.MeanDays},
+		{"median", s.MedianDays, expected.MedianDays},
+		{"p90", s.P90Days, expected.P90Days},
+		{"on time percent", s.OnTimePercent, expected.OnTimePercent},
+		{"early percent", s.EarlyPercent, expected.EarlyPercent},
+	} {
+		if math.Abs(f.actual-f.expected) > 1e-9 {
+			t.Errorf("expected %s of %g, got %g", f.name, f.expected, f.actual)
+		}
+	}
+}
+
+func TestNewStatsInterpolatesPercentiles(t *testing.T) {
+	s := NewStats(tasksWithDays(4, 1, 2, 3))
+
+	if s.MedianDays != 2.5 {
+		t.Errorf("expected a median of 2.5, got %g", s.MedianDays)
+	}
+	if math.Abs(s.P90Days-3.7) > 1e-9 {
+		t.Errorf("expected a p90 of 3.7, got %g", s.P90Days)
+	}
+}
+
+func TestNewStatsWithoutTasks(t *testing.T) {
+	if s := NewStats(nil); s != (Stats{}) {
+		t.Errorf("expected zero stats, got %+v", s)
+	}
+
+	s := NewStats(tasksWithDays(-3))
+	if s.MaxDays != -3 || s.MedianDays != -3 || s.P90Days != -3 || s.OnTimePercent != 100 {
+		t.Errorf("expected a single early task's stats, got %+v", s)
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/metrics"
+	"github.com/robdimsdale/wl"
+)
+
+type cachingTaskSourceFactory struct {
+	store    Store
+	upstream tardy.TaskSourceFactory
+	syncs    *metrics.Counter
+}
+
+// NewCachingTaskSourceFactory returns a TaskSourceFactory whose task
+// sources answer from the provided Store, first syncing any lists whose
+// revision has changed in the upstream task source.
+func NewCachingTaskSourceFactory(
+	store Store,
+	upstream tardy.TaskSourceFactory,
+	registry *metrics.Registry,
+) tardy.TaskSourceFactory {
+	return &cachingTaskSourceFactory{
+		store:    store,
+		upstream: upstream,
+		syncs: registry.NewCounter(
+			"tardy_cache_syncs_total",
+			"Syncs of a user's cached tasks, by result: hit if nothing had changed, miss if changes were downloaded, or error.",
+			"result",
+		),
+	}
+}
+
+func (f *cachingTaskSourceFactory) NewTaskSource(logger lager.Logger, accessToken string) tardy.TaskSource {
+	logger = logger.Session("caching-task-source")
+
+	return &cachingTaskSource{
+		logger:   logger,
+		factory:  f,
+		upstream: f.upstream.NewTaskSource(logger, accessToken),
+	}
+}
+
+type cachingTaskSource struct {
+	logger   lager.Logger
+	factory  *cachingTaskSourceFactory
+	upstream tardy.TaskSource
+
+	once    sync.Once
+	data    UserData
+	root    wl.Root
+	syncErr error
+}
+
+func (s *cachingTaskSource) CompletedTasks() ([]wl.Task, error) {
+	return s.tasks(func(t wl.Task) bool { return t.Completed })
+}
+
+func (s *cachingTaskSource) OpenTasks() ([]wl.Task, error) {
+	return s.tasks(func(t wl.Task) bool { return !t.Completed })
+}
+
+func (s *cachingTaskSource) TasksForListID(listID uint, completed bool) ([]wl.Task, error) {
+	return s.tasks(func(t wl.Task) bool {
+		return t.ListID == listID && t.Completed == completed
+	})
+}
+
+func (s *cachingTaskSource) Lists() ([]wl.List, error) {
+	data, err := s.synced()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []wl.List{}
+	for _, l := range data.Lists {
+		lists = append(lists, l)
+	}
+	sort.Sort(listsByID(lists))
+
+	return lists, nil
+}
+
+func (s *cachingTaskSource) Users() ([]wl.User, error) {
+	data, err := s.synced()
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Users, nil
+}
+
+func (s *cachingTaskSource) Root() (wl.Root, error) {
+	_, err := s.synced()
+	if err != nil {
+		return wl.Root{}, err
+	}
+
+	return s.root, nil
+}
+
+func (s *cachingTaskSource) tasks(include func(wl.Task) bool) ([]wl.Task, error) {
+	data, err := s.synced()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []wl.Task{}
+	for _, t := range data.Tasks {
+		if include(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Sort(tasksByID(tasks))
+
+	return tasks, nil
+}
+
+// synced syncs with the upstream task source at most once
+// for the lifetime of the task source.
+func (s *cachingTaskSource) synced() (UserData, error) {
+	s.once.Do(func() {
+		s.data, s.syncErr = s.sync()
+	})
+	return s.data, s.syncErr
+}
+
+func (s *cachingTaskSource) sync() (UserData, error) {
+	logger := s.logger.Session("sync")
+
+	root, err := s.upstream.Root()
+	if err != nil {
+		logger.Error("failed to fetch root", err)
+		s.factory.syncs.Inc("error")
+		return UserData{}, err
+	}
+	s.root = root
+
+	logger = logger.WithData(lager.Data{"userID": root.UserID})
+
+	// Syncing inside Update serializes syncs for a single user so that
+	// concurrent requests do not download the same changes twice.
+	var synced UserData
+	result := "miss"
+	err = s.factory.store.Update(root.UserID, func(data *UserData) error {
+		synced = *data
+
+		if data.RootRevision == root.Revision {
+			logger.Debug("root revision unchanged", lager.Data{"revision": root.Revision})
+			result = "hit"
+			return ErrUnchanged
+		}
+
+		logger.Debug("root revision changed", lager.Data{
+			"storedRevision": data.RootRevision,
+			"revision":       root.Revision,
+		})
+
+		err := s.syncLists(logger, *data)
+		if err != nil {
+			return err
+		}
+
+		users, err := s.upstream.Users()
+		if err != nil {
+			logger.Error("failed to fetch users", err)
+			return err
+		}
+		data.Users = users
+
+		data.RootRevision = root.Revision
+		data.SyncedAt = time.Now().UTC()
+
+		synced = *data
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to sync", err)
+		s.factory.syncs.Inc("error")
+		return UserData{}, err
+	}
+
+	s.factory.syncs.Inc(result)
+	return synced, nil
+}
+
+func (s *cachingTaskSource) syncLists(logger lager.Logger, data UserData) error {
+	lists, err := s.upstream.Lists()
+	if err != nil {
+		logger.Error("failed to fetch lists", err)
+		return err
+	}
+
+	currentListIDs := map[uint]bool{}
+	for _, list := range lists {
+		currentListIDs[list.ID] = true
+
+		stored, ok := data.Lists[list.ID]
+		if ok && stored.Revision == list.Revision {
+			continue
+		}
+
+		pushed := data.PushedChanges[list.ID]
+		if ok && list.Revision > stored.Revision && list.Revision-stored.Revision == pushed {
+			logger.Debug("list updated by webhooks", lager.Data{
+				"listID":         list.ID,
+				"storedRevision": stored.Revision,
+				"revision":       list.Revision,
+			})
+			data.Lists[list.ID] = list
+			continue
+		}
+
+		logger.Debug("syncing list", lager.Data{
+			"listID":         list.ID,
+			"storedRevision": stored.Revision,
+			"revision":       list.Revision,
+			"pushedChanges":  pushed,
+		})
+
+		err := s.syncList(data, list.ID)
+		if err != nil {
+			logger.Error("failed to sync list", err, lager.Data{"listID": list.ID})
+			return err
+		}
+		data.Lists[list.ID] = list
+	}
+
+	// Every list is now at its upstream revision, so changes pushed
+	// from here on are measured against that.
+	for id := range data.PushedChanges {
+		delete(data.PushedChanges, id)
+	}
+
+	for id := range data.Lists {
+		if !currentListIDs[id] {
+			logger.Debug("removing deleted list", lager.Data{"listID": id})
+			delete(data.Lists, id)
+			removeTasksForList(data, id)
+		}
+	}
+
+	return nil
+}
+
+// syncList replaces the stored tasks for the list with
+// the list's current tasks from the upstream task source.
+func (s *cachingTaskSource) syncList(data UserData, listID uint) error {
+	completedTasks, err := s.upstream.TasksForListID(listID, true)
+	if err != nil {
+		return err
+	}
+
+	openTasks, err := s.upstream.TasksForListID(listID, false)
+	if err != nil {
+		return err
+	}
+
+	removeTasksForList(data, listID)
+
+	for _, t := range completedTasks {
+		data.Tasks[t.ID] = t
+	}
+	for _, t := range openTasks {
+		data.Tasks[t.ID] = t
+	}
+
+	return nil
+}
+
+// removeTasksForList deletes every stored task belonging to the list.
+func removeTasksForList(data UserData, listID uint) {
+	for id, t := range data.Tasks {
+		if t.ListID == listID {
+			delete(data.Tasks, id)
+		}
+	}
+}
+
+type tasksByID []wl.Task
+
+func (t tasksByID) Len() int           { return len(t) }
+func (t tasksByID) Less(i, j int) bool { return t[i].ID < t[j].ID }
+func (t tasksByID) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+type listsByID []wl.List
+
+func (l listsByID) Len() int           { return len(l) }
+func (l listsByID) Less(i, j int) bool { return l[i].ID < l[j].ID }
+func (l listsByID) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
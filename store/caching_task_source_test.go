@@ -0,0 +1,144 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/metrics"
+	"github.com/robdimsdale/wl"
+)
+
+// fakeUpstream serves a single list and counts the downloads of its tasks.
+type fakeUpstream struct {
+	root      wl.Root
+	list      wl.List
+	tasks     []wl.Task
+	downloads int
+}
+
+func (f *fakeUpstream) NewTaskSource(logger lager.Logger, accessToken string) tardy.TaskSource {
+	return f
+}
+
+func (f *fakeUpstream) CompletedTasks() ([]wl.Task, error) { return nil, nil }
+func (f *fakeUpstream) OpenTasks() ([]wl.Task, error)      { return nil, nil }
+func (f *fakeUpstream) Lists() ([]wl.List, error)          { return []wl.List{f.list}, nil }
+func (f *fakeUpstream) Users() ([]wl.User, error)          { return []wl.User{}, nil }
+func (f *fakeUpstream) Root() (wl.Root, error)             { return f.root, nil }
+
+func (f *fakeUpstream) TasksForListID(listID uint, completed bool) ([]wl.Task, error) {
+	if completed {
+		f.downloads++
+	}
+
+	tasks := []wl.Task{}
+	for _, t := range f.tasks {
+		if t.ListID == listID && t.Completed == completed {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// change applies a change to a task upstream, raising the revisions
+// of the list and root as Wunderlist does.
+func (f *fakeUpstream) change(task wl.Task) {
+	f.tasks = append(f.tasks, task)
+	f.list.Revision++
+	f.root.Revision++
+}
+
+func newSyncFixture(t *testing.T) (*fakeUpstream, Store, tardy.TaskSourceFactory, func()) {
+	dir, err := ioutil.TempDir("", "tardy-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := &fakeUpstream{
+		root: wl.Root{UserID: 1, Revision: 1},
+		list: wl.List{ID: 10, Revision: 1},
+	}
+	factory := NewCachingTaskSourceFactory(s, upstream, metrics.NewRegistry())
+
+	return upstream, s, factory, func() { os.RemoveAll(dir) }
+}
+
+func syncTasks(t *testing.T, factory tardy.TaskSourceFactory) []wl.Task {
+	tasks, err := factory.NewTaskSource(lager.NewLogger("test"), "token").CompletedTasks()
+	if err != nil {
+		t.Fatalf("failed to sync: %s", err)
+	}
+	return tasks
+}
+
+func push(t *testing.T, s Store, task wl.Task) {
+	err := s.Update(1, func(data *UserData) error {
+		if !data.PutTask(task) {
+			return ErrUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncTakesListsKeptCurrentByWebhooks(t *testing.T) {
+	upstream, s, factory, cleanup := newSyncFixture(t)
+	defer cleanup()
+
+	syncTasks(t, factory)
+
+	task := wl.Task{ID: 100, ListID: 10, Revision: 1, Completed: true}
+	upstream.change(task)
+	push(t, s, task)
+	// Redelivered events must not be counted again.
+	push(t, s, task)
+
+	tasks := syncTasks(t, factory)
+
+	if upstream.downloads != 1 {
+		t.Errorf("expected the pushed list not to be downloaded again, got %d downloads", upstream.downloads)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("expected the pushed task, got %+v", tasks)
+	}
+}
+
+func TestSyncDownloadsListsWithMissedChanges(t *testing.T) {
+	upstream, s, factory, cleanup := newSyncFixture(t)
+	defer cleanup()
+
+	syncTasks(t, factory)
+
+	pushed := wl.Task{ID: 100, ListID: 10, Revision: 1, Completed: true}
+	upstream.change(pushed)
+	push(t, s, pushed)
+	// This change's event is dropped.
+	upstream.change(wl.Task{ID: 101, ListID: 10, Revision: 1, Completed: true})
+
+	tasks := syncTasks(t, factory)
+
+	if upstream.downloads != 2 {
+		t.Errorf("expected the list to be downloaded again, got %d downloads", upstream.downloads)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("expected both tasks, got %+v", tasks)
+	}
+
+	data, err := s.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.PushedChanges) != 0 {
+		t.Errorf("expected pushed changes to be cleared by the sync, got %v", data.PushedChanges)
+	}
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/tardy/metrics"
+	"github.com/robdimsdale/wl"
+)
+
+// RegisterLatenessMetrics registers a gauge of each user's cached tasks
+// by lateness, calculated with the user's settings applied to the
+// provided rules. Every user's data is read on each scrape, so this is
+// only suitable for small numbers of users.
+func RegisterLatenessMetrics(logger lager.Logger, registry *metrics.Registry, s Store, rules lateness.Rules) {
+	logger = logger.Session("lateness-metrics")
+
+	registry.NewGaugeFunc(
+		"tardy_user_tasks",
+		"Cached tasks with due dates by user and status: late or on_time if completed, including early, or overdue if open.",
+		[]string{"user_id", "status"},
+		func() []metrics.Sample {
+			return latenessSamples(logger, s, rules, time.Now())
+		},
+	)
+}
+
+func latenessSamples(logger lager.Logger, s Store, rules lateness.Rules, now time.Time) []metrics.Sample {
+	userIDs, err := s.UserIDs()
+	if err != nil {
+		logger.Error("failed to list users", err)
+		return nil
+	}
+	sort.Sort(uintSlice(userIDs))
+
+	var samples []metrics.Sample
+	for _, userID := range userIDs {
+		data, err := s.Load(userID)
+		if err != nil {
+			logger.Error("failed to load user data", err, lager.Data{"userID": userID})
+			continue
+		}
+
+		lists := make([]wl.List, 0, len(data.Lists))
+		for _, l := range data.Lists {
+			lists = append(lists, l)
+		}
+		converter := tardy.NewConverter(lists, data.Users, data.Settings.Apply(rules))
+
+		var completed []tardy.Task
+		overdue := 0
+		for _, t := range data.Tasks {
+			if t.Completed {
+				if task, ok := converter.Task(t); ok {
+					completed = append(completed, task)
+				}
+			} else if _, ok := converter.OverdueTask(t, now); ok {
+				overdue++
+			}
+		}
+		stats := tardy.NewStats(completed)
+
+		id := strconv.FormatUint(uint64(userID), 10)
+		samples = append(samples,
+			metrics.Sample{LabelValues: []string{id, "late"}, Value: float64(stats.LateCount)},
+			metrics.Sample{LabelValues: []string{id, "on_time"}, Value: float64(stats.OnTimeCount)},
+			metrics.Sample{LabelValues: []string{id, "overdue"}, Value: float64(overdue)},
+		)
+	}
+	return samples
+}
+
+type uintSlice []uint
+
+func (s uintSlice) Len() int           { return len(s) }
+func (s uintSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uintSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robdimsdale/tardy/filesystem"
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/wl"
+)
+
+//go:generate counterfeiter . Store
+
+// Store persists a copy of each user's task data so that it can be
+// served without re-downloading it from the task source.
+type Store interface {
+	// Load returns the stored data for the user,
+	// or empty UserData if nothing has been stored yet.
+	Load(userID uint) (UserData, error)
+	Save(userID uint, data UserData) error
+
+	// Update loads the user's data, applies fn to it and saves the result,
+	// without any other Load, Save or Update for the user interleaving.
+	// Nothing is saved if fn returns an error. If fn returns ErrUnchanged,
+	// nothing is saved and Update returns nil.
+	Update(userID uint, fn func(data *UserData) error) error
+
+	// UserIDs returns the IDs of every user with stored data.
+	UserIDs() ([]uint, error)
+}
+
+// ErrUnchanged is returned by functions passed to Update which
+// did not modify the data, so that it is not rewritten.
+var ErrUnchanged = errors.New("user data unchanged")
+
+// UserData is the cached copy of a single user's lists, tasks and users.
+// The revisions it holds are compared against the task source to
+// determine what has changed since the last sync.
+type UserData struct {
+	RootRevision uint             `json:"root_revision"`
+	Lists        map[uint]wl.List `json:"lists"`
+	Tasks        map[uint]wl.Task `json:"tasks"`
+	Users        []wl.User        `json:"users"`
+	SyncedAt     time.Time        `json:"synced_at"`
+	Settings     Settings         `json:"settings"`
+
+	// PushedChanges counts the changes webhook events have made to each
+	// list's tasks since the list was last synced. Every change raises a
+	// list's revision by one, so if its revision has risen by exactly
+	// that many the stored tasks are current, and the next sync takes the
+	// new revision without downloading them again. Otherwise a change was
+	// missed, such as a dropped event, and the list is synced as usual.
+	PushedChanges map[uint]uint `json:"pushed_changes,omitempty"`
+}
+
+// Settings are the user's preferences.
+// Zero values mean the server's defaults are used.
+type Settings struct {
+	LatenessMode lateness.Mode `json:"lateness_mode,omitempty"`
+}
+
+// Apply overrides the provided rules with any non-zero settings.
+func (s Settings) Apply(rules lateness.Rules) lateness.Rules {
+	if s.LatenessMode != "" {
+		rules.Mode = s.LatenessMode
+	}
+	return rules
+}
+
+func newUserData() UserData {
+	return UserData{
+		Lists:         map[uint]wl.List{},
+		Tasks:         map[uint]wl.Task{},
+		Users:         []wl.User{},
+		PushedChanges: map[uint]uint{},
+	}
+}
+
+// PutTask adds or replaces the task pushed by a webhook event if its
+// list is known and it is newer than the stored task.
+// It reports whether the task was stored.
+func (d *UserData) PutTask(task wl.Task) bool {
+	if _, ok := d.Lists[task.ListID]; !ok {
+		return false
+	}
+
+	// A redelivered event changes nothing, and must not be counted twice.
+	stored, ok := d.Tasks[task.ID]
+	if ok && stored.Revision >= task.Revision {
+		return false
+	}
+
+	d.Tasks[task.ID] = task
+	d.countPushed(task.ListID)
+	if ok && stored.ListID != task.ListID {
+		d.countPushed(stored.ListID)
+	}
+	return true
+}
+
+// ClearCache removes everything downloaded from the task source,
+// keeping the user's settings, so that the next sync starts afresh.
+func (d *UserData) ClearCache() {
+	settings := d.Settings
+	*d = newUserData()
+	d.Settings = settings
+}
+
+// DeleteTask removes the task deleted by a webhook event.
+// It reports whether the task was stored.
+func (d *UserData) DeleteTask(taskID uint) bool {
+	task, ok := d.Tasks[taskID]
+	if !ok {
+		return false
+	}
+
+	delete(d.Tasks, taskID)
+	d.countPushed(task.ListID)
+	return true
+}
+
+func (d *UserData) countPushed(listID uint) {
+	if d.PushedChanges == nil {
+		d.PushedChanges = map[uint]uint{}
+	}
+	d.PushedChanges[listID]++
+}
+
+type fileStore struct {
+	dir string
+
+	mu        sync.Mutex
+	userLocks map[uint]*sync.Mutex
+}
+
+// NewFileStore returns a Store which keeps one JSON file per user
+// in the provided directory, creating the directory if required.
+func NewFileStore(dir string) (Store, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileStore{
+		dir:       dir,
+		userLocks: map[uint]*sync.Mutex{},
+	}, nil
+}
+
+func (s *fileStore) Load(userID uint) (UserData, error) {
+	l := s.userLock(userID)
+	l.Lock()
+	defer l.Unlock()
+
+	return s.load(userID)
+}
+
+func (s *fileStore) Save(userID uint, data UserData) error {
+	l := s.userLock(userID)
+	l.Lock()
+	defer l.Unlock()
+
+	return s.save(userID, data)
+}
+
+func (s *fileStore) Update(userID uint, fn func(data *UserData) error) error {
+	l := s.userLock(userID)
+	l.Lock()
+	defer l.Unlock()
+
+	data, err := s.load(userID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(&data)
+	if err == ErrUnchanged {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.save(userID, data)
+}
+
+func (s *fileStore) UserIDs() ([]uint, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []uint
+	for _, name := range names {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), ".json"), 10, 0)
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+	return userIDs, nil
+}
+
+func (s *fileStore) userLock(userID uint) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.userLocks[userID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.userLocks[userID] = l
+	}
+	return l
+}
+
+func (s *fileStore) load(userID uint) (UserData, error) {
+	b, err := ioutil.ReadFile(s.path(userID))
+	if os.IsNotExist(err) {
+		return newUserData(), nil
+	}
+	if err != nil {
+		return UserData{}, err
+	}
+
+	data := newUserData()
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return UserData{}, fmt.Errorf("failed to decode data for user %d: %s", userID, err.Error())
+	}
+
+	return data, nil
+}
+
+func (s *fileStore) save(userID uint, data UserData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return filesystem.WriteFileAtomic(s.path(userID), b)
+}
+
+func (s *fileStore) path(userID uint) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d.json", userID))
+}
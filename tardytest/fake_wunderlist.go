@@ -0,0 +1,537 @@
+package tardytest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robdimsdale/wl"
+)
+
+const dueDateFormat = "2006-01-02"
+
+// FakeWunderlist is an in-memory fake of the parts of the Wunderlist
+// OAuth and REST APIs that tardy uses.
+// It authorizes every login attempt immediately for a single user.
+type FakeWunderlist struct {
+	clientID     string
+	clientSecret string
+	accessToken  string
+	handler      http.Handler
+
+	mu       sync.Mutex
+	codes    map[string]bool
+	user     wl.User
+	users    []wl.User
+	lists    []wl.List
+	tasks    []wl.Task
+	webhooks []wl.Webhook
+	revision uint
+	nextID   uint
+}
+
+// NewFakeWunderlist returns a FakeWunderlist which accepts the provided
+// OAuth client credentials. It contains a single user and no lists.
+func NewFakeWunderlist(clientID string, clientSecret string) *FakeWunderlist {
+	f := &FakeWunderlist{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		accessToken:  randomHex(),
+		codes:        map[string]bool{},
+		revision:     1,
+		nextID:       1,
+	}
+
+	f.user = wl.User{
+		ID:        f.newID(),
+		Name:      "Tardy Test",
+		Email:     "tardy@example.com",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Revision:  1,
+	}
+	f.users = []wl.User{f.user}
+
+	rtr := mux.NewRouter()
+
+	rtr.HandleFunc("/oauth/authorize", f.authorize).Methods("GET")
+	rtr.HandleFunc("/oauth/access_token", f.exchangeCode).Methods("POST")
+
+	a := rtr.PathPrefix("/api/v1").Subrouter()
+	a.HandleFunc("/user", f.authenticated(f.getUser)).Methods("GET")
+	a.HandleFunc("/users", f.authenticated(f.getUsers)).Methods("GET")
+	a.HandleFunc("/root", f.authenticated(f.getRoot)).Methods("GET")
+	a.HandleFunc("/lists", f.authenticated(f.getLists)).Methods("GET")
+	a.HandleFunc("/lists/{id}", f.authenticated(f.getList)).Methods("GET")
+	a.HandleFunc("/tasks", f.authenticated(f.getTasks)).Methods("GET")
+	a.HandleFunc("/tasks/{id}", f.authenticated(f.getTask)).Methods("GET")
+	a.HandleFunc("/webhooks", f.authenticated(f.getWebhooks)).Methods("GET")
+	a.HandleFunc("/webhooks", f.authenticated(f.createWebhook)).Methods("POST")
+	a.HandleFunc("/webhooks/{id}", f.authenticated(f.deleteWebhook)).Methods("DELETE")
+
+	f.handler = rtr
+
+	return f
+}
+
+func (f *FakeWunderlist) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.handler.ServeHTTP(w, r)
+}
+
+// AccessToken returns the token issued in exchange for any valid code.
+func (f *FakeWunderlist) AccessToken() string {
+	return f.accessToken
+}
+
+// User returns the user who owns the access token.
+func (f *FakeWunderlist) User() wl.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.user
+}
+
+// AddUser adds another user who is visible to the authorized user,
+// e.g. as a task assignee.
+func (f *FakeWunderlist) AddUser(name string) wl.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user := wl.User{
+		ID:        f.newID(),
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Revision:  1,
+	}
+	f.users = append(f.users, user)
+	f.revision++
+
+	return user
+}
+
+// AddList creates a new list with the provided title.
+func (f *FakeWunderlist) AddList(title string) wl.List {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	list := wl.List{
+		ID:         f.newID(),
+		Title:      title,
+		CreatedAt:  time.Now().UTC(),
+		ListType:   "list",
+		TypeString: "list",
+		Revision:   1,
+	}
+	f.lists = append(f.lists, list)
+	f.revision++
+
+	return list
+}
+
+// Webhooks returns the webhooks registered for all lists.
+func (f *FakeWunderlist) Webhooks() []wl.Webhook {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	webhooks := make([]wl.Webhook, len(f.webhooks))
+	copy(webhooks, f.webhooks)
+	return webhooks
+}
+
+// AddTask stores the provided task, assigning it an ID and revision.
+// The task's ListID must refer to a list previously returned by AddList.
+// Any webhooks registered for the list are notified before it returns.
+func (f *FakeWunderlist) AddTask(task wl.Task) (wl.Task, error) {
+	f.mu.Lock()
+
+	if f.listIndex(task.ListID) < 0 {
+		f.mu.Unlock()
+		return wl.Task{}, fmt.Errorf("list %d not found", task.ListID)
+	}
+
+	task.ID = f.newID()
+	task.Revision = 1
+	if (task.CreatedAt == time.Time{}) {
+		task.CreatedAt = time.Now().UTC()
+	}
+	if task.CreatedByID == 0 {
+		task.CreatedByID = f.user.ID
+	}
+
+	f.tasks = append(f.tasks, task)
+	f.touchList(task.ListID)
+
+	urls := f.webhookURLs(task.ListID)
+	f.mu.Unlock()
+
+	f.notify(urls, "create", task)
+
+	return task, nil
+}
+
+// UpdateTask replaces the stored task with the same ID,
+// incrementing its revision.
+// Any webhooks registered for the list are notified before it returns.
+func (f *FakeWunderlist) UpdateTask(task wl.Task) (wl.Task, error) {
+	f.mu.Lock()
+
+	for i, t := range f.tasks {
+		if t.ID == task.ID {
+			task.Revision = t.Revision + 1
+			f.tasks[i] = task
+			f.touchList(task.ListID)
+			if t.ListID != task.ListID {
+				f.touchList(t.ListID)
+			}
+
+			urls := f.webhookURLs(task.ListID)
+			f.mu.Unlock()
+
+			f.notify(urls, "update", task)
+
+			return task, nil
+		}
+	}
+
+	f.mu.Unlock()
+	return wl.Task{}, fmt.Errorf("task %d not found", task.ID)
+}
+
+// webhookURLs must be called with the lock held.
+func (f *FakeWunderlist) webhookURLs(listID uint) []string {
+	urls := []string{}
+	for _, w := range f.webhooks {
+		if w.ListID == listID {
+			urls = append(urls, w.URL)
+		}
+	}
+	return urls
+}
+
+// notify must be called without the lock held, as the receiver
+// may call back into the fake.
+func (f *FakeWunderlist) notify(urls []string, operation string, task wl.Task) {
+	body, err := json.Marshal(map[string]interface{}{
+		"operation": operation,
+		"user_id":   f.user.ID,
+		"subject": map[string]interface{}{
+			"id":       task.ID,
+			"type":     "task",
+			"revision": task.Revision,
+			"parents": []map[string]interface{}{
+				{"id": task.ListID, "type": "list"},
+			},
+		},
+		"after": toTransport(task),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, u := range urls {
+		resp, err := http.Post(u, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// newID must be called with the lock held.
+func (f *FakeWunderlist) newID() uint {
+	id := f.nextID
+	f.nextID++
+	return id
+}
+
+// listIndex must be called with the lock held.
+func (f *FakeWunderlist) listIndex(listID uint) int {
+	for i, l := range f.lists {
+		if l.ID == listID {
+			return i
+		}
+	}
+	return -1
+}
+
+// touchList must be called with the lock held.
+func (f *FakeWunderlist) touchList(listID uint) {
+	if i := f.listIndex(listID); i >= 0 {
+		f.lists[i].Revision++
+	}
+	f.revision++
+}
+
+func (f *FakeWunderlist) authorize(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	if values.Get("client_id") != f.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(values.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomHex()
+
+	f.mu.Lock()
+	f.codes[code] = true
+	f.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", values.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (f *FakeWunderlist) exchangeCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		Code         string `json:"code"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ClientID != f.clientID || req.ClientSecret != f.clientSecret {
+		http.Error(w, "invalid client credentials", http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	valid := f.codes[req.Code]
+	delete(f.codes, req.Code)
+	f.mu.Unlock()
+
+	if !valid {
+		http.Error(w, "invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, map[string]string{"access_token": f.accessToken})
+}
+
+func (f *FakeWunderlist) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Access-Token") != f.accessToken ||
+			r.Header.Get("X-Client-ID") != f.clientID {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		next(w, r)
+	}
+}
+
+func (f *FakeWunderlist) getUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, f.user)
+}
+
+func (f *FakeWunderlist) getUsers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, f.users)
+}
+
+func (f *FakeWunderlist) getRoot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, wl.Root{
+		ID:       f.user.ID,
+		Revision: f.revision,
+		UserID:   f.user.ID,
+	})
+}
+
+func (f *FakeWunderlist) getLists(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, f.lists)
+}
+
+func (f *FakeWunderlist) getList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	i := f.listIndex(uint(id))
+	if i < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, f.lists[i])
+}
+
+func (f *FakeWunderlist) getTasks(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	listID, err := strconv.ParseUint(values.Get("list_id"), 10, 0)
+	if err != nil || f.listIndex(uint(listID)) < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	completed := values.Get("completed") == "true"
+
+	tasks := []transportTask{}
+	for _, t := range f.tasks {
+		if t.ListID == uint(listID) && t.Completed == completed {
+			tasks = append(tasks, toTransport(t))
+		}
+	}
+
+	writeJSON(w, tasks)
+}
+
+func (f *FakeWunderlist) getTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	for _, t := range f.tasks {
+		if t.ID == uint(id) {
+			writeJSON(w, toTransport(t))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (f *FakeWunderlist) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	listID, err := strconv.ParseUint(r.URL.Query().Get("list_id"), 10, 0)
+	if err != nil || f.listIndex(uint(listID)) < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	webhooks := []wl.Webhook{}
+	for _, wh := range f.webhooks {
+		if wh.ListID == uint(listID) {
+			webhooks = append(webhooks, wh)
+		}
+	}
+
+	writeJSON(w, webhooks)
+}
+
+func (f *FakeWunderlist) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhook wl.Webhook
+	err := json.NewDecoder(r.Body).Decode(&webhook)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if f.listIndex(webhook.ListID) < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	webhook.ID = f.newID()
+	webhook.CreatedAt = time.Now().UTC()
+	webhook.UpdatedAt = webhook.CreatedAt
+	f.webhooks = append(f.webhooks, webhook)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (f *FakeWunderlist) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	for i, wh := range f.webhooks {
+		if wh.ID == uint(id) {
+			f.webhooks = append(f.webhooks[:i], f.webhooks[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// transportTask mirrors the representation of a task on the wire,
+// where the due date is a plain calendar date.
+type transportTask struct {
+	ID              uint      `json:"id"`
+	AssigneeID      uint      `json:"assignee_id,omitempty"`
+	AssignerID      uint      `json:"assigner_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedByID     uint      `json:"created_by_id"`
+	DueDate         string    `json:"due_date,omitempty"`
+	ListID          uint      `json:"list_id"`
+	Revision        uint      `json:"revision"`
+	Starred         bool      `json:"starred"`
+	Title           string    `json:"title"`
+	Completed       bool      `json:"completed"`
+	CompletedAt     time.Time `json:"completed_at"`
+	CompletedByID   uint      `json:"completed_by,omitempty"`
+	RecurrenceType  string    `json:"recurrence_type,omitempty"`
+	RecurrenceCount uint      `json:"recurrence_count,omitempty"`
+}
+
+func toTransport(t wl.Task) transportTask {
+	var dueDate string
+	if (t.DueDate != time.Time{}) {
+		dueDate = t.DueDate.Format(dueDateFormat)
+	}
+
+	return transportTask{
+		ID:              t.ID,
+		AssigneeID:      t.AssigneeID,
+		AssignerID:      t.AssignerID,
+		CreatedAt:       t.CreatedAt,
+		CreatedByID:     t.CreatedByID,
+		DueDate:         dueDate,
+		ListID:          t.ListID,
+		Revision:        t.Revision,
+		Starred:         t.Starred,
+		Title:           t.Title,
+		Completed:       t.Completed,
+		CompletedAt:     t.CompletedAt,
+		CompletedByID:   t.CompletedByID,
+		RecurrenceType:  t.RecurrenceType,
+		RecurrenceCount: t.RecurrenceCount,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+package tardytest
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/robdimsdale/wl"
+)
+
+// AddSampleData populates the fake with a few lists of completed and
+// open tasks spread over the preceding days, some finished early,
+// some late and some still overdue. The same data is produced for the
+// same seed.
+func (f *FakeWunderlist) AddSampleData(seed int64, days int) error {
+	r := rand.New(rand.NewSource(seed))
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	assignee := f.AddUser("Sample Assignee")
+
+	for _, title := range []string{"Chores", "Work", "Errands"} {
+		list := f.AddList(title)
+
+		for i := 0; i < days; i++ {
+			if r.Intn(3) != 0 {
+				continue
+			}
+
+			dueDate := today.AddDate(0, 0, -i)
+			task := wl.Task{
+				ListID:    list.ID,
+				Title:     fmt.Sprintf("%s task %d", title, i),
+				DueDate:   dueDate,
+				CreatedAt: dueDate.AddDate(0, 0, -r.Intn(14)-1),
+				Starred:   r.Intn(5) == 0,
+			}
+
+			if r.Intn(2) == 0 {
+				task.AssigneeID = assignee.ID
+			}
+
+			if r.Intn(6) == 0 {
+				task.RecurrenceType = "week"
+				task.RecurrenceCount = 1
+			}
+
+			// A few tasks are forgotten and are still open, overdue.
+			completedAt := dueDate.Add(time.Duration(r.Intn(24*10)-24*3) * time.Hour)
+			if completedAt.Before(today) && r.Intn(15) != 0 {
+				task.Completed = true
+				task.CompletedAt = completedAt
+				task.CompletedByID = f.User().ID
+			}
+
+			_, err := f.AddTask(task)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package tardytest
+
+import "net/http/httptest"
+
+// Server runs a FakeWunderlist on a local loopback address.
+type Server struct {
+	*httptest.Server
+	Fake *FakeWunderlist
+}
+
+// NewServer starts a FakeWunderlist which accepts the provided
+// OAuth client credentials. Callers should Close it when done.
+func NewServer(clientID string, clientSecret string) *Server {
+	fake := NewFakeWunderlist(clientID, clientSecret)
+
+	return &Server{
+		Server: httptest.NewServer(fake),
+		Fake:   fake,
+	}
+}
+
+// AuthURL is the base URL for the OAuth endpoints,
+// equivalent to https://www.wunderlist.com.
+func (s *Server) AuthURL() string {
+	return s.URL
+}
+
+// APIURL is the base URL for the REST endpoints,
+// equivalent to wl.APIURL.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/v1"
+}
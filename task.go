@@ -1,11 +1,102 @@
 package tardy
 
-import "time"
+import (
+	"time"
+
+	"github.com/robdimsdale/tardy/lateness"
+	"github.com/robdimsdale/wl"
+)
 
 type Task struct {
-	ID          uint      `json:"id"`
-	Title       string    `json:"title"`
-	DueDate     time.Time `json:"due_date"`
-	CompletedAt time.Time `json:"completed_at"`
-	Days        int       `json:"days"`
+	ID             uint      `json:"id"`
+	Title          string    `json:"title"`
+	ListID         uint      `json:"list_id"`
+	ListTitle      string    `json:"list_title"`
+	AssigneeID     uint      `json:"assignee_id"`
+	AssigneeName   string    `json:"assignee_name"`
+	CompletedByID  uint      `json:"completed_by_id"`
+	Starred        bool      `json:"starred"`
+	RecurrenceType string    `json:"recurrence_type"`
+	CreatedAt      time.Time `json:"created_at"`
+	DueDate        time.Time `json:"due_date"`
+	CompletedAt    time.Time `json:"completed_at"`
+	Days           int       `json:"days"`
+
+	// LatenessSeconds is the precise time between the deadline
+	// and completion, ignoring any grace period.
+	LatenessSeconds int64 `json:"lateness_seconds"`
+}
+
+// Converter builds Tasks from Wunderlist tasks,
+// resolving list and assignee IDs into names
+// and calculating lateness according to the provided rules.
+type Converter struct {
+	listTitles map[uint]string
+	userNames  map[uint]string
+	rules      lateness.Rules
+}
+
+func NewConverter(lists []wl.List, users []wl.User, rules lateness.Rules) Converter {
+	c := Converter{
+		listTitles: map[uint]string{},
+		userNames:  map[uint]string{},
+		rules:      rules,
+	}
+
+	for _, l := range lists {
+		c.listTitles[l.ID] = l.Title
+	}
+
+	for _, u := range users {
+		c.userNames[u.ID] = u.Name
+	}
+
+	return c
+}
+
+// Task converts a completed task into a Task.
+// It returns false for tasks without a due date,
+// as their lateness is undefined.
+func (c Converter) Task(t wl.Task) (Task, bool) {
+	if (t.DueDate == time.Time{}) {
+		return Task{}, false
+	}
+
+	return c.task(t, c.rules.Calculate(t.DueDate, t.CompletedAt)), true
+}
+
+// OverdueTask converts an open task into a Task whose lateness is
+// how overdue it is at now. It returns false for tasks without a
+// due date and tasks which are not yet late.
+func (c Converter) OverdueTask(t wl.Task, now time.Time) (Task, bool) {
+	if (t.DueDate == time.Time{}) {
+		return Task{}, false
+	}
+
+	l := c.rules.Calculate(t.DueDate, now)
+	if l.Days <= 0 {
+		return Task{}, false
+	}
+
+	return c.task(t, l), true
+}
+
+func (c Converter) task(t wl.Task, l lateness.Lateness) Task {
+	return Task{
+		ID:             t.ID,
+		Title:          t.Title,
+		ListID:         t.ListID,
+		ListTitle:      c.listTitles[t.ListID],
+		AssigneeID:     t.AssigneeID,
+		AssigneeName:   c.userNames[t.AssigneeID],
+		CompletedByID:  t.CompletedByID,
+		Starred:        t.Starred,
+		RecurrenceType: t.RecurrenceType,
+		CreatedAt:      t.CreatedAt,
+		DueDate:        t.DueDate,
+		CompletedAt:    t.CompletedAt,
+		Days:           l.Days,
+
+		LatenessSeconds: int64(l.Duration / time.Second),
+	}
 }
@@ -0,0 +1,39 @@
+package tardy
+
+import (
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/wl"
+)
+
+//go:generate counterfeiter . TaskSource
+
+// TaskSource provides read access to the tasks, lists and users
+// held by a task tracker on behalf of a single user.
+type TaskSource interface {
+	CompletedTasks() ([]wl.Task, error)
+	OpenTasks() ([]wl.Task, error)
+	TasksForListID(listID uint, completed bool) ([]wl.Task, error)
+	Lists() ([]wl.List, error)
+	Users() ([]wl.User, error)
+
+	// Root returns the revision of the user's entire object hierarchy,
+	// which changes whenever any list or task changes.
+	Root() (wl.Root, error)
+}
+
+//go:generate counterfeiter . TaskSourceFactory
+
+// TaskSourceFactory creates a TaskSource for the user identified
+// by the provided access token. The task source logs to sessions of
+// logger, so that its logs can be correlated with the request using it.
+type TaskSourceFactory interface {
+	NewTaskSource(logger lager.Logger, accessToken string) TaskSource
+}
+
+//go:generate counterfeiter . WebhookRegistrar
+
+// WebhookRegistrar subscribes tardy to change notifications
+// for every list belonging to the user identified by the access token.
+type WebhookRegistrar interface {
+	RegisterWebhooks(accessToken string) error
+}
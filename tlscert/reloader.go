@@ -0,0 +1,125 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// Reloader serves a certificate and key loaded from files, reloading
+// them when asked so that certificates can be renewed without a restart.
+// If a reload fails, the previous certificate continues to be served.
+type Reloader struct {
+	logger   lager.Logger
+	certFile string
+	keyFile  string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// NewReloader loads the certificate and key, returning an error
+// if they cannot be loaded.
+func NewReloader(logger lager.Logger, certFile string, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		logger:   logger.Session("tls-cert", lager.Data{"cert-file": certFile}),
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate is for use as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Reload loads the certificate and key from their files.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.currentModTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.modTimes = modTimes
+	return nil
+}
+
+// HandleSignals reloads the certificate on SIGHUP.
+func (r *Reloader) HandleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			r.reloadAndLog("sighup")
+		}
+	}()
+}
+
+// Watch reloads the certificate whenever either file's modification
+// time changes, checking every interval.
+func (r *Reloader) Watch(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			modTimes, err := r.currentModTimes()
+			if err != nil {
+				r.logger.Error("failed to check certificate files", err)
+				continue
+			}
+
+			r.mu.RLock()
+			changed := modTimes != r.modTimes
+			r.mu.RUnlock()
+
+			if changed {
+				r.reloadAndLog("file-changed")
+			}
+		}
+	}()
+}
+
+func (r *Reloader) reloadAndLog(reason string) {
+	err := r.Reload()
+	if err != nil {
+		r.logger.Error("failed to reload certificate", err, lager.Data{"reason": reason})
+		return
+	}
+	r.logger.Info("reloaded-certificate", lager.Data{"reason": reason})
+}
+
+func (r *Reloader) currentModTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
@@ -0,0 +1,298 @@
+package tokens
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/robdimsdale/tardy/filesystem"
+	"github.com/robdimsdale/tardy/session"
+)
+
+// Prefix begins every token's secret, making leaked tokens easy to find.
+const Prefix = "tardy_"
+
+// lastUsedResolution limits how often LastUsedAt is written,
+// so that busy scripts do not rewrite the store on every request.
+const lastUsedResolution = time.Minute
+
+var (
+	ErrInvalidToken = errors.New("invalid API token")
+	ErrNotFound     = errors.New("API token not found")
+)
+
+//go:generate counterfeiter . Store
+
+type Store interface {
+	// Create stores a new token acting with the user's Wunderlist access
+	// token, returning the token and its secret. The secret is not
+	// stored and cannot be retrieved later.
+	Create(userID uint, name string, scopes []Scope, accessToken string) (Token, string, error)
+
+	// List returns the user's tokens, oldest first.
+	List(userID uint) ([]Token, error)
+
+	// Revoke deletes one of the user's tokens.
+	Revoke(userID uint, id string) error
+
+	// Authenticate returns the token with the provided secret and the
+	// Wunderlist access token it acts with, recording that it was used.
+	Authenticate(secret string) (Token, string, error)
+}
+
+// storedToken is a Token as persisted. Only a hash of the secret is kept,
+// and the Wunderlist access token is encrypted.
+type storedToken struct {
+	Token
+	SecretHash           string `json:"secret_hash"`
+	EncryptedAccessToken string `json:"encrypted_access_token"`
+}
+
+type fileStore struct {
+	path   string
+	codecs []securecookie.Codec
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a Store which keeps every user's tokens in a single
+// JSON file at path. Access tokens are encrypted with the first key pair
+// and may be decrypted with any of them. Tokens encrypted with an older
+// pair are re-encrypted with the first, so that the older pairs can be
+// removed without revoking the tokens.
+func NewFileStore(path string, keys []session.KeyPair) (Store, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens do not expire, so unlike sessions' the codecs have no maximum age.
+	codecs := session.Codecs(keys)
+	for _, c := range codecs {
+		if sc, ok := c.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(0)
+		}
+	}
+
+	s := &fileStore{
+		path:   path,
+		codecs: codecs,
+	}
+
+	err = s.reencrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *fileStore) Create(userID uint, name string, scopes []Scope, accessToken string) (Token, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return Token{}, "", err
+	}
+
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+
+	encrypted, err := securecookie.EncodeMulti("access-token", accessToken, s.codecs...)
+	if err != nil {
+		return Token{}, "", err
+	}
+
+	t := storedToken{
+		Token: Token{
+			ID:        hex.EncodeToString(securecookie.GenerateRandomKey(8)),
+			UserID:    userID,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedAt: time.Now().UTC(),
+		},
+		SecretHash:           hash(secret),
+		EncryptedAccessToken: encrypted,
+	}
+
+	err = s.save(append(stored, t))
+	if err != nil {
+		return Token{}, "", err
+	}
+
+	return t.Token, secret, nil
+}
+
+func (s *fileStore) List(userID uint) ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []Token{}
+	for _, t := range stored {
+		if t.UserID == userID {
+			tokens = append(tokens, t.Token)
+		}
+	}
+	sort.Sort(tokensByCreatedAt(tokens))
+
+	return tokens, nil
+}
+
+func (s *fileStore) Revoke(userID uint, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	remaining := []storedToken{}
+	for _, t := range stored {
+		if t.UserID != userID || t.ID != id {
+			remaining = append(remaining, t)
+		}
+	}
+
+	if len(remaining) == len(stored) {
+		return ErrNotFound
+	}
+
+	return s.save(remaining)
+}
+
+func (s *fileStore) Authenticate(secret string) (Token, string, error) {
+	if !strings.HasPrefix(secret, Prefix) {
+		return Token{}, "", ErrInvalidToken
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return Token{}, "", err
+	}
+
+	secretHash := hash(secret)
+	for i, t := range stored {
+		if t.SecretHash != secretHash {
+			continue
+		}
+
+		var accessToken string
+		err := securecookie.DecodeMulti("access-token", t.EncryptedAccessToken, &accessToken, s.codecs...)
+		if err != nil {
+			// The key the access token was encrypted with has been removed.
+			return Token{}, "", ErrInvalidToken
+		}
+
+		now := time.Now().UTC()
+		if now.Sub(t.LastUsedAt) >= lastUsedResolution {
+			stored[i].LastUsedAt = now
+			err := s.save(stored)
+			if err != nil {
+				return Token{}, "", err
+			}
+		}
+
+		return stored[i].Token, accessToken, nil
+	}
+
+	return Token{}, "", ErrInvalidToken
+}
+
+// reencrypt encrypts with the first codec every access token encrypted
+// with another. Tokens whose key has already been removed are left as
+// they are, and fail to authenticate.
+func (s *fileStore) reencrypt() error {
+	if len(s.codecs) < 2 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i, t := range stored {
+		var accessToken string
+		err := securecookie.DecodeMulti("access-token", t.EncryptedAccessToken, &accessToken, s.codecs[0])
+		if err == nil {
+			continue
+		}
+
+		err = securecookie.DecodeMulti("access-token", t.EncryptedAccessToken, &accessToken, s.codecs[1:]...)
+		if err != nil {
+			continue
+		}
+
+		stored[i].EncryptedAccessToken, err = securecookie.EncodeMulti("access-token", accessToken, s.codecs...)
+		if err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return s.save(stored)
+}
+
+func (s *fileStore) load() ([]storedToken, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []storedToken{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored := []storedToken{}
+	err = json.Unmarshal(b, &stored)
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (s *fileStore) save(stored []storedToken) error {
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return filesystem.WriteFileAtomic(s.path, b)
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type tokensByCreatedAt []Token
+
+func (t tokensByCreatedAt) Len() int           { return len(t) }
+func (t tokensByCreatedAt) Less(i, j int) bool { return t[i].CreatedAt.Before(t[j].CreatedAt) }
+func (t tokensByCreatedAt) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
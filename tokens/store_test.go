@@ -0,0 +1,59 @@
+package tokens
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robdimsdale/tardy/session"
+)
+
+func TestTokensSurviveKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tardy-tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+
+	oldKeys := session.GenerateKeyPair()
+	newKeys := session.GenerateKeyPair()
+
+	s, err := NewFileStore(path, []session.KeyPair{oldKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, secret, err := s.Create(1, "script", []Scope{ScopeReadTasks}, "access-token")
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+
+	// The new pair is added to the front, and then the old pair removed.
+	for _, keys := range [][]session.KeyPair{
+		{newKeys, oldKeys},
+		{newKeys},
+	} {
+		s, err = NewFileStore(path, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, accessToken, err := s.Authenticate(secret)
+		if err != nil {
+			t.Fatalf("failed to authenticate with %d key pairs: %s", len(keys), err)
+		}
+		if accessToken != "access-token" {
+			t.Errorf("expected the access token to be decrypted, got %q", accessToken)
+		}
+	}
+
+	s, err = NewFileStore(path, []session.KeyPair{session.GenerateKeyPair()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = s.Authenticate(secret)
+	if err != ErrInvalidToken {
+		t.Errorf("expected tokens to be revoked when every key is removed, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package tokens
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeReadTasks Scope = "tasks:read"
+	ScopeReadStats Scope = "stats:read"
+	ScopeExport    Scope = "export"
+)
+
+// Scopes are all the scopes which may be granted to a token.
+var Scopes = []Scope{ScopeReadTasks, ScopeReadStats, ScopeExport}
+
+// Token is a personal API token, which grants scripts the same access
+// to the API as the user who created it, limited to its scopes.
+// The token's secret is only known when it is created.
+type Token struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Name       string    `json:"name"`
+	Scopes     []Scope   `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// HasScope reports whether the token has been granted the scope.
+func (t Token) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes validates a list of scope names, removing duplicates.
+func ParseScopes(names []string) ([]Scope, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one scope must be provided")
+	}
+
+	scopes := []Scope{}
+	seen := map[Scope]bool{}
+	for _, name := range names {
+		scope := Scope(name)
+		if !validScope(scope) {
+			valid := make([]string, len(Scopes))
+			for i, s := range Scopes {
+				valid[i] = string(s)
+			}
+			return nil, fmt.Errorf("scope must be one of %s, got %q", strings.Join(valid, ", "), name)
+		}
+
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes, nil
+}
+
+func validScope(scope Scope) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,12 @@
 
 $(document).ready ( function(){
 
+// State-changing requests must carry the session's CSRF token.
+var csrfToken = $('meta[name="csrf-token"]').attr("content");
+if (csrfToken) {
+  $.ajaxSetup({ headers: { "X-CSRF-Token": csrfToken } });
+}
+
 function getDate(d) {
   return new Date(d.due_date);
 }
@@ -63,7 +69,7 @@ var margin = {top: 20, right: 30, bottom: 30, left: 40},
             .style("fill-opacity", 1)
           })
           .append("svg:title")
-          .text(function(d) { return d.id + "," + d.days; })
+          .text(function(d) { return d.title + " (" + d.list_title + "): " + d.days + " days"; })
           ;
   };
 
@@ -73,5 +79,102 @@ var margin = {top: 20, right: 30, bottom: 30, left: 40},
         .append("g")
         .attr("transform", "translate(" + margin.left + "," + margin.top + ")");
 
-    d3.json("/api/v1/tasks", render);
+    // Lateness is calculated on calendar days in the browser's time zone.
+    var tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
+    d3.json("/api/v1/tasks?tz=" + encodeURIComponent(tz), render);
+
+    // Overdue tasks are returned most overdue first.
+    function renderOverdue(data) {
+      d3.select(".overdue-count").text(data.length);
+
+      var rows = d3.select(".overdue tbody")
+          .selectAll("tr")
+          .data(data)
+        .enter().append("tr")
+          .attr("class", function(d) { return d.days >= 7 ? "danger" : d.days >= 2 ? "warning" : null; });
+
+      rows.append("td")
+          .append("a")
+          .attr("href", function(d) { return "https://wunderlist.com/#/tasks/" + d.id; })
+          .attr("target", "_blank")
+          .text(function(d) { return d.title; });
+
+      rows.append("td").text(function(d) { return d.list_title; });
+      rows.append("td").text(function(d) { return d.assignee_id ? d.assignee_name : "Unassigned"; });
+      rows.append("td").text(function(d) { return d.due_date.substring(0, 10); });
+      rows.append("td").text(function(d) { return d.days; });
+  };
+
+    d3.json("/api/v1/tasks/overdue?tz=" + encodeURIComponent(tz), function(error, data) {
+      if (error) {
+        d3.select(".overdue-count").text("?");
+        return;
+      }
+      renderOverdue(data);
+    });
+
+    function formatTime(t) {
+      if (!t || t.indexOf("0001-") === 0) {
+        return "Never";
+      }
+      return new Date(t).toLocaleString();
+    }
+
+    function showTokenError(xhr) {
+      $(".token-error").text(xhr.responseText || "Request failed").removeClass("hidden");
+    }
+
+    function loadTokens() {
+      $.getJSON("/api/v1/tokens", function(tokens) {
+        var rows = d3.select(".api-tokens tbody")
+            .selectAll("tr")
+            .data(tokens, function(d) { return d.id; });
+
+        rows.exit().remove();
+
+        var added = rows.enter().append("tr");
+        added.append("td").text(function(d) { return d.name; });
+        added.append("td").text(function(d) { return d.scopes.join(", "); });
+        added.append("td").text(function(d) { return formatTime(d.created_at); });
+        added.append("td").attr("class", "last-used");
+        added.append("td")
+            .append("button")
+            .attr("class", "btn btn-danger btn-xs")
+            .text("Revoke")
+            .on("click", function(d) {
+              if (!window.confirm("Revoke " + d.name + "? Scripts using it will stop working.")) {
+                return;
+              }
+              $.ajax({ url: "/api/v1/tokens/" + encodeURIComponent(d.id), type: "DELETE" })
+                .done(loadTokens)
+                .fail(showTokenError);
+            });
+
+        rows.select(".last-used").text(function(d) { return formatTime(d.last_used_at); });
+      });
+    }
+
+    $(".create-token").on("submit", function(event) {
+      event.preventDefault();
+      $(".new-token, .token-error").addClass("hidden");
+
+      var form = $(this);
+      var scopes = form.find("input[name=scopes]:checked").map(function() { return this.value; }).get();
+
+      $.ajax({
+        url: "/api/v1/tokens",
+        type: "POST",
+        contentType: "application/json",
+        data: JSON.stringify({ name: form.find("input[name=name]").val(), scopes: scopes })
+      }).done(function(token) {
+        $(".new-token code").text(token.secret);
+        $(".new-token").removeClass("hidden");
+        form[0].reset();
+        loadTokens();
+      }).fail(showTokenError);
+    });
+
+    if ($(".api-tokens").length) {
+      loadTokens();
+    }
 });
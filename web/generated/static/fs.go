@@ -187,31 +187,51 @@ var _escData = map[string]*_escFile{
 
 	"/static/css/home.css": {
 		local: "web/assets/static/css/home.css",
-		size:  189,
+		size:  222,
 		compressed: `
-H4sIAAAJbogA/0zMwQrCMAyA4XufIuDVjXqtZx8kc9lWDGlpKlRk727cFL0l5M/XD1jg6abIHEArEQ98
-p7NbneuxRYVKrb6DJDXAyecGiqKdUonTX5axLsfPzFHIXgB2VZIYCKaXdKMAB+/9ti+YqSsko1kyB7iW
-qPkyzqQ73H70xo12Znx8xdW9AgAA//97HuIFvQAAAA==
+H4sIAAAAAAAC/03OwQrCMAwG4HufIuDVjeqxnn2QzmVdMaaliVAR391uU/SW8P/5SD/4Ak8zRSIHoog0
+0B1P5mVM72sUUKy6FBKrg4PNFcSzdIIlTn+17HXef2aKjO0EYFM5cQOh6SVd0cHOWrvus8/YFeSxWRwc
+XEqUfB4DygbXH71yY4vJP77iUqEU0l3X9OZLiNxpyg6O7c+l8AZw71tD3gAAAA==
 `,
 	},
 
 	"/static/js/home.js": {
 		local: "web/assets/static/js/home.js",
-		size:  2360,
+		size:  6126,
 		compressed: `
-H4sIAAAJbogA/7RW227qOBR9z1dYnnlwRGoCnc4DaB4qzev5gqo6chMDPk1iZG8gEeLfz7YdLgbSVpVO
-JCTf9vLy2jfoxkpiwagC5jRJ/malLja1bCDlRoqyI4wsNk0BSjcs3SfJcUKWEv4XIFmZkn1CiJGwMQ1p
-5I6EZV5u5M8Sh+k8OSTJVhhSC7NUDfmP7EGvZ2SaZ8So5Qpm5BGHbxpA12FcyQWu/pMfMoQmZKdKWKHd
-ZPJvTh56HO4OnWceKRxfSTfG80/5xXG88zwJl80Tf/70JiObUhqGrEV4lfsc8xbBykcOqpbcFqKSLO13
-rz9e6lqohuFp2QLq6NGys4hOr6NaZw3n5JAOQhrRLCV7QV28EK9pzztw6wI3T4tXqpHCfMruZWCfOKTa
-0XekLzjve8boVtHZ+SHNPkQQ7fcRXj/TIbg3y690aJ9bZXsttksucBbp0Duujda0Uc5HNMQDvVL2a4jd
-XUQXnRd4xUoY4GK9xhBjdEkjGwFgGC0qYS3NCG2Ju+reEUAJ7EKb2h3zk8rFT55RMjqG/YjQNLZFlhXz
-8nyPT3eHj8fs7mFaWckCnnGb8jdhYjMXFSG/Tqsc5cKkS09kDJp/yOcGNey3dCDNWnZONJ9pt7bdkG3H
-fghY+YjG/AvBOwQSHDCE5HHEm2Ud62GwHHUsH0LzuY5gUxLHV+OUUMV7dE+mzgUrfDvVlHrHNWqKzADW
-djYe7zauwFXKAi90Pf5rDMK+27GLnpIrLEMXEIeba2uNzUJvpbl+YnSxyxYfAgxWGB3RHvcxq0I3wQ5h
-hB9O8/zqXCzB5HrbQoeJRxeqqh70WhQKnANz/pR+4QEb+ES6P/CC6ZceMBmmf0wOLEQzUFDJOAMAmw27
-G3bOr64mZMHJvvjG2M7phz6JXdnzadyXvSAD5X7t4sqr54UGPYo68yjqzDemp2Q5la2LPj2K+/Sl8Z2K
-9XFxpDe8ghbRda5k9hLgs39ZFy1jsVbj7STkCGKGfwfu7wz+fgcAAP//oImuMTgJAAA=
+H4sIAAAAAAAC/7VYYW/jNhL97l/BYwOshNi0k7ZXwEFuUexugR72mmKTAocugoCRaFsbifSRVGxv6v9+
+M6QkU7LspClqYDcSyRnODN+8GYqWRhBjdZbYCzoYnESpSspCSBszLXi6IRGZlTKxmZJR/DQYjMfk2nIr
+RsmCy3km50SL/5XCWEOK0liScK03xC5AqTAGpN4Y8u7600/Eqgch2eCRa5IYPbvBV3JJTqI3hbD8s+SF
+uKQ4M3Ir6e2bmHFrdUQTJS0YROOLQTYjUSMdk6cBISeMf+Hra2HLZfREFmCz0GZKngj97wg3Hrm1dBrs
+uiVb0LUdDGrPyFzY9+BUlHqdGrRpSaRYET/M0lLcpfDo5dCJgmtwHzx4smo5JeeTIdHZfGGn5Ft4vFfW
+qsI/52IGo99NtkNQTcgqS+0C5M7O/jkho0oPw0W7N6fJL18IfIb130+C5bDn7sVvdjFw6xuftJAQigis
+5t4r/KHla1CWfstsVghmEp6LKK5muz+WqoJnMoLVYo1n4LQNd4jAeNXR2sXwAuJ7UKUG2IjoM8TFBeI2
+ruz2tm28bc4slmdScP2sdZ8PzBPUVKD5aHRg81NlMRwr35iLbTw8qoGvX6/h9rk4+OMdTjpxWP+4zkwV
+i8c54/DWikN1cOvWmNIZnhH1eKCdyL5M46ZXI6Iz0Ae5ry3jyyVALKJz2pKpkjbnxtAhoWuCW/UtsRAC
+M1O6wGXuJUf8TIaUnNawPyU0bsuClXnkwvM6ezY99jidmz6dRuQisT/CNGX3XLfFEBU+v5pRBuGCpIsb
+YzSIH7VnT6ufX9MDabaOdonmMm1fdnNIdhP9h9uFQzTknwfvISX+AA5pcnr4vYk2UaUG6GgTTQ5pc7kO
+ys5JG18SI5ElD619htmOsPxvlclUrZiCmIJl1i7NdDxelUhweWYsS1Qx/mZsuXkwY0RPyjKgoUDFdm/b
+QkHlU49Cd10cdPLfQyCyC0BHa445zGa+NEKF0Nw9nk8mnXXtEJx1p43dQOLRWZbnI7XkSWbxACfs+/gF
+DpT2mdD9DR6cv8iBs8Pm18kBRDS1mc1FOwMsFJuoF3Ypc8uRFkjkDxrP/64ZjafEDyMm3TJ8oB1QIjK2
+Vaa7hgRzveJGHyvK3FhgVycGvoqftsr3aat874k2GdVwW1DMT9vFPBTuobXjDEr37KJD2t0OebUKAfR0
+H0FOQsNGoEYAGyYlKkoJtBFYFWQKQXIRhY4He7t7rVZGaOjtsIsgX5UUrImm/Qqh/FnanCFN3cCCn8BE
+biNsKY3KH0V6tcSjhQLkupDfQdwnKxzAF4PgHvNlNn488yn91n69RPuFTFQqfvv08ztVLEEGKpP9Gg+r
+Tifw5grSGjo24qQJ16LCD3hUKGhSVTU/yzSQR1/bVGnodE8hPiodo0SV2Jt60OJyiLmc20W7+mK8OgCr
+jbD3Kt20EyCoOvYVNcfqYxXnQGK54/3XJfmBvCU0xeZEUzINJs5xYsW1hKYfZ2SZ5xeuk672Qh93NqS0
+N+V5n2kLLWaHLHsp3ffXHQuQF5hz9O4+5/LhTxLNcf+Oyu94ySt5jQ44sGwuhbjLUgh+8I63JTgC+pus
+hlL6+l3quw0z5T3eBOUcuwMoU39BJXbFlXRNtP25Pa7S4LkcbzYSWisNzUsrMfFe6CbC6vdsttK3NGgS
+vPH1+7b2e58O/JIGGA1zzBzLId9Ftm3aPyz54w9iGXQxYn01i+hkMjkb0ZhcXl6SSWhzDfpfBHYm+8a0
+76VwSbfqo0KKvvYHVxvXMc0s1MrdfT9gkKL1IgjUCYTHXblHLoJ1cGAN0jWcgRE3MIAe0E/+rk9mPMsF
+gkCLAkL7DqkF0jhLU7hqH7AhVzx1NgDt7zZnkJ7/vr76JYCFWxPSgR8Jw3SAUkGD/3pgelj1KK/WzOql
+D5Kkp5mGEaq0EOvMFzeMRRROo50cgpKCoX5pD1XvIOiWvjzLkAXCHP3TCkwCHbVhXxRckpEj47+gLUB/
+yhItsH+44/Z5lZ37EPy1I+hs0+OB6fSn1cx9Cc2TpL3d6+7GZSWBfyNf5Nzj2nRlPEF8Eo8AiO5c/62l
+23hXqV9dXRIlodsoapVVl+p4HHqxt+Q60dnSGlIa/KaWWWgw85wY7NVWSj/AIKPx/hZd2mr67c67/0oW
+PZFS51A32rk2PkC87hY1JHazFCDz/sPHDzcfaLvMNl9ipIh2Cd6zAhkjatNQ3DZ6P6+avA4w8VIEosgd
+inQxuG3TE7KfR2v13TF2xwulsMhaVysgZBnQuntlS+3+vhczXuY2ajZBrcDSXuWQdPgV0LzHmEGviH64
+L6PuwnYRzPh8hTlcwmYZQj6Ty9L6z6d++naaLETy4IJV8OUuVkGoUDN75Hnp+AM5OKCtGivNYfRhhu6+
+eFX4+PXq+iYYrb7a3vhJSFBIGHe9HGMXECxE3p0SrAHMdx/ZbANARY+mBxzF/27BPfAgAoB6v6d1eBqA
+gmcOmO1CEiZR65wIJkCNMP+52gjAhg2A2hI4Xv9c8QPzP09usZCKAB6kVQx3yDySJU12IKmctGodmFFd
+ORrP9tVvB6jh/2We8yHuFwAA
 `,
 	},
 
@@ -185,27 +185,54 @@ func FSMustString(useLocal bool, name string) string {
 
 var _escData = map[string]*_escFile{
 
+	"/templates/error.html.tmpl": {
+		local: "web/assets/templates/error.html.tmpl",
+		size:  840,
+		compressed: `
+H4sIAAAAAAAC/2xTwY7TMBC99ysGn0lMlwtCdiS0W4meWMEiwXEaT2MLx87a05Yqyr+jNG1JEJdk/N6b
+Zz+P3PeG9i4QCEopJjEMK/Xm6cvjy8/nDVhufbVS4w88hkYLCqJaAShLaMYCQLXECLXFlIm1OPC++CDm
+lGXuCno9uKMWP4rvn4rH2HbIbudJQB0DU2AtthtNpqFFZ8CWtDg6OnUx8Ux8coatNnR0NRWXxVtwwbFD
+X+QaPen1zYgde6peMJkzFLAZMyo5gZPAu/ALEnktMp89ZUvEAmyivRZSBmITsNzFyJkTdrUJZR1beQfk
++/KhfCfrnP9iZetCWec8XZW83ZXaRXO+7mrcEWqPOWsxpkIXKF2PvGRTPN3xf/t88TsX64cZP05mPaVV
+0q4XxKwVPSWGy7cwGJrZ3ldxV32LLbF1oYETBYZTimNpnSfwEc1IsHUZOmyohGdPmAk4nQEbdKFUslta
+9r3bQ/mVXg+Uefs0DKqrVG7R++oKwvbpI6g6Gqr6fqGUF1DJST5a9z0FMwzzfNK44yIw3qYobrl3HGDH
+oTC0x4NnUX2OLSmJ1eq/LrPFvVRymqKS0+O4HeTPAN7L66hIAwAA
+`,
+	},
+
 	"/templates/head.html.tmpl": {
 		local: "web/assets/templates/head.html.tmpl",
-		size:  793,
+		size:  871,
 		compressed: `
-H4sIAAAJbogA/5RTzW7UMBC+71MYn4lNW4EQiiOh0gMnOBQJjrP2bO3g2Kk9mzaK+u44yZaWRZW6yiHj
-sb8ff5lMk8GdC8i4RTD84WFTv/ny7fL61/crZqnzzaaeX8xDuFEcA282jNXz2bkoZYcETFtIGUnxPe2q
-j/z5liXqK7zdu0Hxn9WPz9Vl7Hogt/XImY6BMBTc1yuF5gb/QQboUPHB4V0fEz07fOcMWWVwcBqrZfGW
-ueDIga+yBo/q7JGIHHlsriGZsZbrYrPuZJ1cT4zGvogQ3pNsYYC1y1lOWnEpdTQo2ts9plHo2Mm1rM7F
-WXk6F0SbeVPLFdWcQByQTACxjZEyJei1CYvA34a8EOfinWzzU+slQe/Cb5bQK55p9JgtYhGyCXenKOl8
-LFU6/Citxf38RfOnEo4JbRbax73ZeUi40EIL99K7bZbmojC/Fx9K8ej8vzl5uskrrpKpjI1ejNrY4cHf
-qzM/wNsD+jjIWq5DPU0YTPkN/gQAAP//nN+ZSRkDAAA=
+H4sIAAAAAAAC/5VTTU/jMBC991cYn4nNh3aFUBwJFZA47Wq3K8HRtSfEwbGDPS1UUf/7OkkLAYQE8sHj
+53nzZsbjrtNQGgeEViA13W5n+cHlr/ni7vcVqbCxxSzvN2KluxcUHC1mhOS9b28kswGURFUyREBBV1hm
+Z3R6VSG2GTyuzFrQ2+zfRTb3TSvRLC1QorxDcIl3cyVA38MbppMNCLo28NT6gBPnJ6OxEhrWRkE2HA6J
+cQaNtFlU0oI43gdCgxaKhQx6k/PxMFx0nSkJm//9c73wD+C226mkiqHMsMcnol03dadF14HTqV2jTlTB
+tEhw0yY+wjPyWq7liFISgxKUc+U1sPpxBWHDlG/4aGYn7DitxjhWR1rkfGQV3wjsALWTbOk9RgyyVdoN
+Ai8AP2Un7IjX8RX6TNAa90ACWEEjbizECiAJVQHK7yip+F4qIelR3hQ1ZN/PRzxPzdGujkxZv9KllQGG
+sLKWz9yaZeT6NEX+wX4mY5/5h6l7reQLpURMQ6iGRCvfwC6/L/d8R6937PeNzPn4RfZT8h9YXnuVZwMA
+AA==
 `,
 	},
 
 	"/templates/home.html.tmpl": {
 		local: "web/assets/templates/home.html.tmpl",
-		size:  270,
+		size:  2737,
 		compressed: `
-H4sIAAAJbogA/1yPQc7CIBCF1/9/ipE9IXWNnMILjGVsm9DSAKk2pHd3KorR3cz7eI95OVu6DhOB6P1I
-M3Yktu0/50Tj7DDtOqHdNQB98XY1PPBohwVahzGeROunhBwRRGHfNPhb1X99Tt6jbI7M/xj1jTljsKtW
-PH0cii01uCzvLS5dDesxJGG0Yu114vMt6IOUUE8EKXeqVanCX6XRGe5Lk+WOjwAAAP//+BtKZQ4BAAA=
+H4sIAAAAAAAC/81WX2vbMBB/Xj/Fzc9zQte34RRKx6BQ1rL2fSjWxRaVJU+Sk4bQ776T5DiO43qFbWwP
+kaX7f6fT77LbcVwJhZCUusKaFZi8vJztdg6rWjLn6ch4AjOiAmRLzbeXtKEtF2vIJbN2keRaOUZGTBJ5
+x1yjNx19qCfTZ5uef+zxAXY7sYLZrS4K5Dcq+O2UV9pUe22pC904qBspUyOK0iVQoSs1XyT3dw+PCbDc
+Ca0WyTxKHjkhW0LVpO62NS6SUnCOKgHFKjrl1qy+O/3kKWsmGyLtdrPrh29fHj3x5WVoatk4p1VryzbL
+SlAwbZhLp4B+KdWZNdKFva2SS0oQKKpsHpX7FrO5z/O4Jqg4leIdMcvzy0dm+Dab0+5Q1zkVtit/POxP
+dl10JS+ZoUpkc6IdBH7jtvoSNVMoIawpZ6pAA3qNhjc4LNhQKfVdJlQxkPPZXhwLOuEkmbuLdik3Inel
+ZrzAvcs0142KqZIIfcqLQRD9irUkx5YS9+biIaxkTFGDWOSnETof+5Dq6eaUGMTp+uxTNqfNK/xbYd0U
+/8paUSjEKZnPzTSbbe2+UuNyRD1JwEuOJJu5gAvEPeBDT8UX8Li9jwo/1rmTSPBX2rV9nawWaXj59o+3
+7NX9DUTTb+vFE4e+uKfe+pDo96lQ0gN6bpDwOyaTjFxvz3xQK4xu6mS0X/pI6fD5gG1B0aO/0RKClIe2
+FkX9mgBNkRxLLTmaRRLgEyLD4I9GGBx7OafFiN1gPKbA7CHXNdqjwdAqSrakG+2gDvOnpX5uC/Lr1PYK
++wRs8NMfAR75IXxHgg7Ox8KO0H2i8JahURtRMbPthsZ1uNTYRmOTY3x6DO+bSTTU6n5NbZPnaC0o3MRe
+gXYWnmZyrestuFLY6B+U3nwA4WAjpKQDjTYEW+qNAlbQ34FPkOWaI8FC+Jy94ZJfC7IdJsFtisZo00U5
+9nL+GbB/pb6ZQt3YuVMS8YL55HRg1kFjp4X+Y0w/PIfIgOx9mkL3HxLS1MtSdwe/hJWuome11/sJg1Y3
+f7EKAAA=
 `,
 	},
 
@@ -4,7 +4,11 @@ import (
 	"html/template"
 	"net/http"
 
+	"github.com/gorilla/sessions"
 	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/session"
+	"github.com/robdimsdale/tardy/tokens"
 )
 
 type Handler interface {
@@ -14,19 +18,56 @@ type Handler interface {
 type handler struct {
 	logger    lager.Logger
 	templates *template.Template
+	store     sessions.Store
 }
 
 func NewHandler(
 	logger lager.Logger,
 	templates *template.Template,
+	store sessions.Store,
 ) Handler {
 	return &handler{
 		logger:    logger.Session("handler-home"),
 		templates: templates,
+		store:     store,
 	}
 }
 
 func (h handler) Home(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
 	h.logger.Debug("received request")
-	h.templates.ExecuteTemplate(w, "homepage", nil)
+
+	s, err := h.store.Get(r, "session-name")
+	if err != nil {
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := homepage{
+		Scopes: tokens.Scopes,
+	}
+	if accessToken, ok := s.Values["accessToken"].(string); ok && accessToken != "" {
+		data.LoggedIn = true
+
+		// Sessions created before CSRF tokens were introduced
+		// are given one the next time the page is loaded.
+		var created bool
+		data.CSRFToken, created = session.CSRFToken(s)
+		if created {
+			err := s.Save(r, w)
+			if err != nil {
+				h.logger.Error("failed to save session", err)
+			}
+		}
+	}
+
+	h.templates.ExecuteTemplate(w, "homepage", data)
+}
+
+type homepage struct {
+	LoggedIn  bool
+	CSRFToken string
+	Scopes    []tokens.Scope
 }
@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/background"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/session"
+	"github.com/robdimsdale/tardy/store"
 )
 
 //go:generate counterfeiter . Handler
@@ -22,72 +28,146 @@ type Handler interface {
 
 type handler struct {
 	logger        lager.Logger
-	cookieHandler *securecookie.SecureCookie
-	cookieMaxAge  int
-	store         *sessions.CookieStore
+	codecs        []securecookie.Codec
+	store         sessions.Store
 	clientID      string
 	clientSecret  string
+	authURL       string
 	redirectURI   string
-	secretState   string
+	stateTTL      time.Duration
+	usedStates    *usedStates
+	secureCookies bool
+
+	webhookRegistrar  tardy.WebhookRegistrar
+	taskSourceFactory tardy.TaskSourceFactory
+	userStore         store.Store
+	jobs              *background.Jobs
 }
 
 func NewHandler(
 	logger lager.Logger,
-	cookieHandler *securecookie.SecureCookie,
-	cookieMaxAge int,
-	store *sessions.CookieStore,
+	codecs []securecookie.Codec,
+	store sessions.Store,
 	clientID string,
 	clientSecret string,
+	authURL string,
 	redirectURI string,
-	secretState string,
+	stateTTL time.Duration,
+	secureCookies bool,
+	webhookRegistrar tardy.WebhookRegistrar,
+	taskSourceFactory tardy.TaskSourceFactory,
+	userStore store.Store,
+	jobs *background.Jobs,
 ) Handler {
 	return &handler{
 		logger:        logger.Session("handler-login"),
-		cookieHandler: cookieHandler,
-		cookieMaxAge:  cookieMaxAge,
+		codecs:        codecs,
 		store:         store,
 		clientID:      clientID,
 		clientSecret:  clientSecret,
+		authURL:       authURL,
 		redirectURI:   redirectURI,
-		secretState:   secretState,
+		stateTTL:      stateTTL,
+		usedStates:    newUsedStates(),
+		secureCookies: secureCookies,
+
+		webhookRegistrar:  webhookRegistrar,
+		taskSourceFactory: taskSourceFactory,
+		userStore:         userStore,
+		jobs:              jobs,
 	}
 }
 
+// LoginGET starts a login by redirecting to the OAuth provider with a new
+// state, which is remembered in a cookie along with the page to return to
+// after login, provided by the redirect parameter.
 func (h handler) LoginGET(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
 	h.logger.Debug("received request")
 
+	state, err := newState()
+	if err != nil {
+		h.logger.Error("failed to generate state", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	logins := append(h.pendingLogins(r, now), pendingLogin{
+		State:     state,
+		Redirect:  safeRedirect(r.URL.Query().Get("redirect")),
+		ExpiresAt: now.Add(h.stateTTL),
+	})
+
+	err = h.setPendingLogins(w, logins)
+	if err != nil {
+		h.logger.Error("failed to set state cookie", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	redirectQueryString := fmt.Sprintf(
 		"client_id=%s&redirect_uri=%s&state=%s",
 		h.clientID,
 		h.redirectURI,
-		url.QueryEscape(h.secretState),
+		url.QueryEscape(state),
 	)
 
 	http.Redirect(
 		w,
 		r,
 		fmt.Sprintf(
-			"https://www.wunderlist.com/oauth/authorize?%s",
+			"%s/oauth/authorize?%s",
+			h.authURL,
 			redirectQueryString,
 		),
 		http.StatusFound,
 	)
 }
 
+// LoginResponse completes a login if the returned state matches an
+// unexpired, unused login started by the same browser.
 func (h handler) LoginResponse(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("received login response", lager.Data{"url": r.URL})
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	h.logger.Debug("received login response", lager.Data{"url": r.URL.Path})
 
 	values := r.URL.Query()
-	returnedState := values["state"][0]
-	if returnedState != h.secretState {
+	returnedState := values.Get("state")
+
+	now := time.Now()
+	logins := h.pendingLogins(r, now)
+
+	var login pendingLogin
+	remaining := []pendingLogin{}
+	for _, l := range logins {
+		if returnedState != "" && l.State == returnedState {
+			login = l
+		} else {
+			remaining = append(remaining, l)
+		}
+	}
+
+	if login.State == "" || !h.usedStates.use(login.State, login.ExpiresAt, now) {
 		// No need to leak any info if we are being impersonated
-		err := fmt.Errorf("returned state %s did not match expected state %s - returning 404", returnedState, h.secretState)
-		h.logger.Error("", err)
+		h.logger.Info("returned state did not match a pending login - returning 404")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	code := values["code"][0]
+	// The state is single-use, whether or not the rest of the login succeeds.
+	err := h.setPendingLogins(w, remaining)
+	if err != nil {
+		h.logger.Error("failed to set state cookie", err)
+	}
+
+	code := values.Get("code")
+	if code == "" {
+		h.logger.Info("login response without code", lager.Data{"error": values.Get("error")})
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
 
 	bodyString := fmt.Sprintf(
 		`{"client_id":"%s","client_secret":"%s","code":"%s"}`,
@@ -96,124 +176,139 @@ func (h handler) LoginResponse(w http.ResponseWriter, r *http.Request) {
 		code,
 	)
 
-	h.logger.Debug("Exchanging code for accessToken with Wunderlist", lager.Data{"code": code})
+	h.logger.Debug("Exchanging code for accessToken with Wunderlist")
 
 	resp, err := http.Post(
-		"https://www.wunderlist.com/oauth/access_token",
+		fmt.Sprintf("%s/oauth/access_token", h.authURL),
 		"application/json",
 		bytes.NewBuffer([]byte(bodyString)),
 	)
 
 	if err != nil {
-		fmt.Printf("error communicating with Wunderlist: %s\n", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		h.logger.Error("failed to exchange code", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("code exchange returned %d", resp.StatusCode)
+		h.logger.Error("failed to exchange code", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
 	var accessTokenResp accessTokenResponse
 	err = json.NewDecoder(resp.Body).Decode(&accessTokenResp)
 	if err != nil {
-		fmt.Printf("error unmarshalling accessToken response: %s\n", err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		h.logger.Error("failed to decode code exchange response", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	h.logger.Debug("completed code exchange: received access_token", lager.Data{"accessToken": accessTokenResp.AccessToken})
-
-	h.setSession(accessTokenResp.AccessToken, r, w)
-	http.Redirect(w, r, "/", http.StatusFound)
-}
+	if accessTokenResp.AccessToken == "" {
+		err := fmt.Errorf("code exchange returned no access token")
+		h.logger.Error("failed to exchange code", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
 
-func (h handler) LogoutPOST(w http.ResponseWriter, r *http.Request) {
-	clearSession(w)
-	http.Redirect(w, r, "/", http.StatusFound)
-}
+	h.logger.Debug("completed code exchange: received access_token")
 
-func (h handler) setSession(
-	accessToken string,
-	r *http.Request,
-	w http.ResponseWriter,
-) {
-	value := map[string]string{
-		"accessToken": accessToken,
-	}
-	encoded, err := h.cookieHandler.Encode("session", value)
-	if err == nil {
-		cookie := &http.Cookie{
-			Name:   "session",
-			Value:  encoded,
-			Path:   "/",
-			MaxAge: h.cookieMaxAge,
-		}
-		http.SetCookie(w, cookie)
+	// The user ID is recorded in the session so that
+	// later requests need not ask Wunderlist for it.
+	root, err := h.taskSourceFactory.NewTaskSource(h.logger, accessTokenResp.AccessToken).Root()
+	if err != nil {
+		h.logger.Error("failed to get root", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
 
-		h.logger.Debug("Getting session")
+	err = h.setSession(accessTokenResp.AccessToken, root.UserID, r, w)
+	if err != nil {
+		h.logger.Error("failed to save session", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		session, err := h.store.Get(r, "session-name")
+	// Registering webhooks makes a request per list,
+	// so do not make the user wait for it.
+	accessToken := accessTokenResp.AccessToken
+	h.jobs.Go(func() {
+		err := h.webhookRegistrar.RegisterWebhooks(accessToken)
 		if err != nil {
-			h.logger.Error("", err)
-			http.Error(w, err.Error(), 500)
-			return
+			h.logger.Error("failed to register webhooks", err)
 		}
+	})
 
-		h.logger.Debug(
-			"Setting access token in session",
-			lager.Data{
-				"accessToken": accessToken,
-			},
-		)
+	http.Redirect(w, r, login.Redirect, http.StatusFound)
+}
 
-		session.Values["accessToken"] = accessToken
-		session.Save(r, w)
+// LogoutPOST destroys the session and the user's cached task data.
+func (h handler) LogoutPOST(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
 
-		h.logger.Debug(
-			"Successfuly saved session",
-			lager.Data{
-				"sessionName": "session-name",
-			},
-		)
+	s, err := h.store.Get(r, "session-name")
+	if err != nil {
+		h.logger.Error("", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		session, err = h.store.Get(r, "session-name")
+	// The user ID is taken from the session rather than the task source,
+	// so that the cache is cleared even if the access token was revoked.
+	if userID, ok := s.Values["userID"].(uint); ok && userID != 0 {
+		err := h.userStore.Update(userID, func(data *store.UserData) error {
+			data.ClearCache()
+			return nil
+		})
 		if err != nil {
-			h.logger.Error("", err)
-			http.Error(w, err.Error(), 500)
-			return
+			// The session is still destroyed, and the cache is
+			// only a copy of data held by the task source.
+			h.logger.Error("failed to clear cached data", err)
 		}
+	}
 
-		fmt.Printf("session %+v\n", session.Values)
-
-		accessTokenInterface := session.Values["accessToken"]
-		if accessTokenInterface == nil {
-			h.logger.Debug("accessToken nil in session - redirecting")
-			return
-		} else {
-			accessToken, ok := accessTokenInterface.(string)
-			if !ok {
-				err := fmt.Errorf("failed to convert %v into string", accessTokenInterface)
-				h.logger.Error("", err)
-				http.Error(w, err.Error(), 500)
-				return
-			}
-
-			if accessToken == "" {
-				h.logger.Debug("accessToken empty in session - redirecting")
-				return
-			} else {
-				h.logger.Debug("accessToken found in session", lager.Data{"accessToken": accessToken})
-				return
-			}
-		}
+	s.Options.MaxAge = -1
+	err = s.Save(r, w)
+	if err != nil {
+		h.logger.Error("failed to destroy session", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
 }
 
-func clearSession(response http.ResponseWriter) {
-	cookie := &http.Cookie{
-		Name:   "session",
-		Value:  "",
-		Path:   "/",
-		MaxAge: -1,
+// setSession stores the access token and the user's ID in a new session,
+// replacing any session from before the login. Only the session's ID is
+// sent to the browser if the store is server-side.
+func (h handler) setSession(
+	accessToken string,
+	userID uint,
+	r *http.Request,
+	w http.ResponseWriter,
+) error {
+	s, err := session.Regenerate(h.store, r, w, "session-name")
+	if err != nil {
+		return err
+	}
+
+	s.Values["accessToken"] = accessToken
+	s.Values["userID"] = userID
+	session.CSRFToken(s)
+	err = s.Save(r, w)
+	if err != nil {
+		return err
 	}
-	http.SetCookie(response, cookie)
+
+	h.logger.Debug(
+		"Successfuly saved session",
+		lager.Data{
+			"sessionName": "session-name",
+		},
+	)
+	return nil
 }
 
 type accessTokenResponse struct {
@@ -0,0 +1,162 @@
+package login
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/securecookie"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	stateCookieName = "oauth-state"
+
+	// maxPendingLogins bounds the size of the state cookie
+	// when a browser starts several logins without finishing them.
+	maxPendingLogins = 5
+)
+
+// pendingLogin is a login attempt which has been sent to the OAuth provider
+// and not yet returned. Pending logins are kept in a signed cookie so that
+// the callback is only accepted from the browser which started the login.
+type pendingLogin struct {
+	State     string
+	Redirect  string
+	ExpiresAt time.Time
+}
+
+func newState() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// safeRedirect returns target if it is a path on this site, or "/" otherwise,
+// so that the login flow cannot be used as an open redirect.
+// Browsers ignore tabs and newlines and treat backslashes as slashes,
+// so either could turn a path into "//evil.com"; both are rejected
+// anywhere in the target, as are escaped leading slashes.
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || !safeRedirectChars(target) {
+		return "/"
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+
+	if strings.HasPrefix(u.Path, "//") || !safeRedirectChars(u.Path) {
+		return "/"
+	}
+
+	return target
+}
+
+func safeRedirectChars(s string) bool {
+	for _, c := range s {
+		if c == '\\' || unicode.IsControl(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// pendingLogins returns the unexpired pending logins from the request's
+// state cookie. A missing or invalid cookie has no pending logins.
+func (h handler) pendingLogins(r *http.Request, now time.Time) []pendingLogin {
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		return nil
+	}
+
+	var logins []pendingLogin
+	err = securecookie.DecodeMulti(stateCookieName, cookie.Value, &logins, h.codecs...)
+	if err != nil {
+		h.logger.Info("ignoring invalid state cookie", lager.Data{"error": err.Error()})
+		return nil
+	}
+
+	unexpired := []pendingLogin{}
+	for _, l := range logins {
+		if now.Before(l.ExpiresAt) {
+			unexpired = append(unexpired, l)
+		}
+	}
+	return unexpired
+}
+
+func (h handler) setPendingLogins(w http.ResponseWriter, logins []pendingLogin) error {
+	if len(logins) == 0 {
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   h.secureCookies,
+		})
+		return nil
+	}
+
+	if len(logins) > maxPendingLogins {
+		logins = logins[len(logins)-maxPendingLogins:]
+	}
+
+	encoded, err := securecookie.EncodeMulti(stateCookieName, logins, h.codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    encoded,
+		Path:     "/",
+		MaxAge:   int(h.stateTTL / time.Second),
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+	})
+	return nil
+}
+
+// usedStates remembers states which have completed a login until they
+// expire, so that a replayed callback is rejected even if it carries
+// an old copy of the state cookie.
+type usedStates struct {
+	mu     sync.Mutex
+	states map[string]time.Time
+}
+
+func newUsedStates() *usedStates {
+	return &usedStates{
+		states: map[string]time.Time{},
+	}
+}
+
+// use marks the state as used, reporting false if it already was.
+func (u *usedStates) use(state string, expiresAt time.Time, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for s, e := range u.states {
+		if !now.Before(e) {
+			delete(u.states, s)
+		}
+	}
+
+	if _, ok := u.states[state]; ok {
+		return false
+	}
+
+	u.states[state] = expiresAt
+	return true
+}
@@ -0,0 +1,32 @@
+package login
+
+import "testing"
+
+func TestSafeRedirect(t *testing.T) {
+	for _, test := range []struct {
+		target   string
+		expected string
+	}{
+		{"/stats", "/stats"},
+		{"/stats?from=2016-01-01#chart", "/stats?from=2016-01-01#chart"},
+		{"/a/b%20c", "/a/b%20c"},
+		{"", "/"},
+		{"stats", "/"},
+		{"//x", "/"},
+		{"/\\x", "/"},
+		{"/\t/x", "/"},
+		{"/\n/x", "/"},
+		{"/%2F/x", "/"},
+		{"/%2f/x", "/"},
+		{"/%5Cx", "/"},
+		{"/%09/x", "/"},
+		{"/stats\\x", "/"},
+		{"https://x", "/"},
+		{"javascript:alert(1)", "/"},
+	} {
+		actual := safeRedirect(test.target)
+		if actual != test.expected {
+			t.Errorf("safeRedirect(%q): expected %q, got %q", test.target, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy/middleware"
+	"github.com/robdimsdale/tardy/store"
+	"github.com/robdimsdale/wl"
+)
+
+// PathPrefix is the path under which webhook callbacks are received.
+// It must be reachable without a browser session.
+const PathPrefix = "/webhooks/wunderlist"
+
+// SecretPath matches the paths of webhook callbacks for
+// logger.Redactor, as their signature authenticates them.
+const SecretPath = PathPrefix + "/*/{secret}"
+
+type Handler interface {
+	Receive(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	logger lager.Logger
+	signer Signer
+	store  store.Store
+}
+
+func NewHandler(
+	logger lager.Logger,
+	signer Signer,
+	store store.Store,
+) Handler {
+	return &handler{
+		logger: logger.Session("webhooks"),
+		signer: signer,
+		store:  store,
+	}
+}
+
+// Receive applies a task event posted by Wunderlist to the stored data
+// of the user identified in the URL. It expects to be routed with
+// {userID} and {signature} path variables.
+func (h handler) Receive(w http.ResponseWriter, r *http.Request) {
+	h.logger = middleware.RequestLogger(r, h.logger)
+
+	vars := mux.Vars(r)
+
+	userID, err := strconv.ParseUint(vars["userID"], 10, 0)
+	if err != nil || !h.signer.Verify(uint(userID), vars["signature"]) {
+		// No need to leak any info if we are being impersonated
+		h.logger.Info("rejecting webhook with invalid signature", lager.Data{"userID": vars["userID"]})
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	logger := h.logger.WithData(lager.Data{"userID": userID})
+
+	var p payload
+	err = json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		logger.Error("failed to decode payload", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Debug("received event", lager.Data{
+		"operation":   p.Operation,
+		"subjectType": p.Subject.Type,
+		"subjectID":   p.Subject.ID,
+	})
+
+	if p.Subject.Type != "task" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = h.store.Update(uint(userID), func(data *store.UserData) error {
+		switch p.Operation {
+		case "create", "update":
+			task, err := p.task()
+			if err != nil {
+				return err
+			}
+			if !data.PutTask(task) {
+				logger.Debug("ignoring event for unknown list or stale revision", lager.Data{"taskID": task.ID})
+				return store.ErrUnchanged
+			}
+		case "delete":
+			if !data.DeleteTask(p.Subject.ID) {
+				return store.ErrUnchanged
+			}
+		default:
+			logger.Debug("ignoring unknown operation", lager.Data{"operation": p.Operation})
+			return store.ErrUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to apply event", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// payload is the body of a Wunderlist webhook request.
+type payload struct {
+	Operation string          `json:"operation"`
+	Subject   subject         `json:"subject"`
+	After     json.RawMessage `json:"after"`
+}
+
+type subject struct {
+	ID       uint   `json:"id"`
+	Type     string `json:"type"`
+	Revision uint   `json:"revision"`
+}
+
+// transportTask is a task as represented in the webhook payload,
+// where the due date is a plain calendar date.
+type transportTask struct {
+	ID              uint      `json:"id"`
+	AssigneeID      uint      `json:"assignee_id"`
+	AssignerID      uint      `json:"assigner_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedByID     uint      `json:"created_by_id"`
+	DueDate         string    `json:"due_date"`
+	ListID          uint      `json:"list_id"`
+	Revision        uint      `json:"revision"`
+	Starred         bool      `json:"starred"`
+	Title           string    `json:"title"`
+	Completed       bool      `json:"completed"`
+	CompletedAt     time.Time `json:"completed_at"`
+	CompletedByID   uint      `json:"completed_by"`
+	RecurrenceType  string    `json:"recurrence_type"`
+	RecurrenceCount uint      `json:"recurrence_count"`
+}
+
+func (p payload) task() (wl.Task, error) {
+	if len(p.After) == 0 {
+		return wl.Task{}, fmt.Errorf("%s event for task %d has no task data", p.Operation, p.Subject.ID)
+	}
+
+	var t transportTask
+	err := json.Unmarshal(p.After, &t)
+	if err != nil {
+		return wl.Task{}, err
+	}
+
+	var dueDate time.Time
+	if t.DueDate != "" {
+		// Due dates are calendar dates, interpreted in the same way as
+		// the Wunderlist client interprets them.
+		dueDate, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(t.DueDate), t.CreatedAt.Location())
+		if err != nil {
+			return wl.Task{}, err
+		}
+	}
+
+	return wl.Task{
+		ID:              t.ID,
+		AssigneeID:      t.AssigneeID,
+		AssignerID:      t.AssignerID,
+		CreatedAt:       t.CreatedAt,
+		CreatedByID:     t.CreatedByID,
+		DueDate:         dueDate,
+		ListID:          t.ListID,
+		Revision:        t.Revision,
+		Starred:         t.Starred,
+		Title:           t.Title,
+		Completed:       t.Completed,
+		CompletedAt:     t.CompletedAt,
+		CompletedByID:   t.CompletedByID,
+		RecurrenceType:  t.RecurrenceType,
+		RecurrenceCount: t.RecurrenceCount,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+//go:generate counterfeiter . Signer
+
+// Signer produces and verifies the per-user signature embedded in
+// webhook callback URLs, so that only Wunderlist, which was given
+// the URL, can post events for a user.
+type Signer interface {
+	Sign(userID uint) string
+	Verify(userID uint, signature string) bool
+
+	// CallbackURL returns the signed URL under baseURL
+	// to which events for the user should be posted.
+	CallbackURL(baseURL string, userID uint) string
+}
+
+type signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) Signer {
+	return &signer{
+		key: key,
+	}
+}
+
+func (s signer) Sign(userID uint) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strconv.FormatUint(uint64(userID), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s signer) Verify(userID uint, signature string) bool {
+	return hmac.Equal([]byte(s.Sign(userID)), []byte(signature))
+}
+
+func (s signer) CallbackURL(baseURL string, userID uint) string {
+	return fmt.Sprintf("%s%s/%d/%s", baseURL, PathPrefix, userID, s.Sign(userID))
+}
@@ -0,0 +1,42 @@
+package wunderlist
+
+import (
+	"time"
+
+	"github.com/robdimsdale/tardy/metrics"
+)
+
+// Metrics records calls made to the Wunderlist API,
+// labelled by the endpoint called.
+type Metrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: registry.NewCounter(
+			"tardy_wunderlist_requests_total",
+			"Calls to the Wunderlist API, by endpoint and result: success or error.",
+			"endpoint", "result",
+		),
+		duration: registry.NewHistogram(
+			"tardy_wunderlist_request_duration_seconds",
+			"Time taken by calls to the Wunderlist API, by endpoint.",
+			metrics.DefaultBuckets,
+			"endpoint",
+		),
+	}
+}
+
+// observe records a call to endpoint which started at start and
+// returned *err. It is intended to be deferred with a named error.
+func (m *Metrics) observe(endpoint string, start time.Time, err *error) {
+	result := "success"
+	if *err != nil {
+		result = "error"
+	}
+
+	m.requests.Inc(endpoint, result)
+	m.duration.Observe(time.Since(start).Seconds(), endpoint)
+}
@@ -0,0 +1,86 @@
+package wunderlist
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/wl"
+	wllogger "github.com/robdimsdale/wl/logger"
+	"github.com/robdimsdale/wl/oauth"
+)
+
+type taskSourceFactory struct {
+	clientID string
+	apiURL   string
+	metrics  *Metrics
+}
+
+func NewTaskSourceFactory(
+	clientID string,
+	apiURL string,
+	metrics *Metrics,
+) tardy.TaskSourceFactory {
+	return &taskSourceFactory{
+		clientID: clientID,
+		apiURL:   apiURL,
+		metrics:  metrics,
+	}
+}
+
+func (f taskSourceFactory) NewTaskSource(logger lager.Logger, accessToken string) tardy.TaskSource {
+	client := oauth.NewClient(
+		accessToken,
+		f.clientID,
+		f.apiURL,
+		wllogger.NewLogger(wllogger.INFO),
+	)
+
+	return &taskSource{
+		logger:  logger.Session("wunderlist"),
+		client:  client,
+		metrics: f.metrics,
+	}
+}
+
+type taskSource struct {
+	logger  lager.Logger
+	client  wl.Client
+	metrics *Metrics
+}
+
+func (s taskSource) CompletedTasks() (tasks []wl.Task, err error) {
+	s.logger.Debug("fetching completed tasks")
+	defer s.metrics.observe("tasks", time.Now(), &err)
+	return s.client.CompletedTasks(true)
+}
+
+func (s taskSource) OpenTasks() (tasks []wl.Task, err error) {
+	s.logger.Debug("fetching open tasks")
+	defer s.metrics.observe("tasks", time.Now(), &err)
+	return s.client.CompletedTasks(false)
+}
+
+func (s taskSource) TasksForListID(listID uint, completed bool) (tasks []wl.Task, err error) {
+	s.logger.Debug("fetching tasks for list", lager.Data{"listID": listID, "completed": completed})
+	defer s.metrics.observe("list_tasks", time.Now(), &err)
+	return s.client.CompletedTasksForListID(listID, completed)
+}
+
+func (s taskSource) Lists() (lists []wl.List, err error) {
+	s.logger.Debug("fetching lists")
+	defer s.metrics.observe("lists", time.Now(), &err)
+	return s.client.Lists()
+}
+
+func (s taskSource) Users() (users []wl.User, err error) {
+	s.logger.Debug("fetching users")
+	defer s.metrics.observe("users", time.Now(), &err)
+	return s.client.Users()
+}
+
+func (s taskSource) Root() (root wl.Root, err error) {
+	s.logger.Debug("fetching root")
+	defer s.metrics.observe("root", time.Now(), &err)
+	return s.client.Root()
+}
@@ -0,0 +1,118 @@
+package wunderlist
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+	"github.com/robdimsdale/tardy"
+	"github.com/robdimsdale/tardy/webhooks"
+	wllogger "github.com/robdimsdale/wl/logger"
+	"github.com/robdimsdale/wl/oauth"
+)
+
+const webhookProcessorType = "generic"
+
+type webhookRegistrar struct {
+	logger          lager.Logger
+	clientID        string
+	apiURL          string
+	callbackBaseURL string
+	signer          webhooks.Signer
+	metrics         *Metrics
+}
+
+// NewWebhookRegistrar returns a WebhookRegistrar which creates webhooks
+// pointing at signed callback URLs under callbackBaseURL.
+func NewWebhookRegistrar(
+	logger lager.Logger,
+	clientID string,
+	apiURL string,
+	callbackBaseURL string,
+	signer webhooks.Signer,
+	metrics *Metrics,
+) tardy.WebhookRegistrar {
+	return &webhookRegistrar{
+		logger:          logger.Session("wunderlist-webhook-registrar"),
+		clientID:        clientID,
+		apiURL:          apiURL,
+		callbackBaseURL: callbackBaseURL,
+		signer:          signer,
+		metrics:         metrics,
+	}
+}
+
+// RegisterWebhooks ensures each of the user's lists has exactly one
+// webhook pointing at the current callback URL. Webhooks pointing at
+// tardy with an out-of-date signature are deleted.
+func (r webhookRegistrar) RegisterWebhooks(accessToken string) error {
+	client := oauth.NewClient(
+		accessToken,
+		r.clientID,
+		r.apiURL,
+		wllogger.NewLogger(wllogger.INFO),
+	)
+
+	start := time.Now()
+	root, err := client.Root()
+	r.metrics.observe("root", start, &err)
+	if err != nil {
+		r.logger.Error("failed to fetch root", err)
+		return err
+	}
+
+	logger := r.logger.WithData(lager.Data{"userID": root.UserID})
+
+	callbackURL := r.signer.CallbackURL(r.callbackBaseURL, root.UserID)
+	callbackPrefix := r.callbackBaseURL + webhooks.PathPrefix
+
+	start = time.Now()
+	lists, err := client.Lists()
+	r.metrics.observe("lists", start, &err)
+	if err != nil {
+		logger.Error("failed to fetch lists", err)
+		return err
+	}
+
+	for _, list := range lists {
+		start := time.Now()
+		existing, err := client.WebhooksForListID(list.ID)
+		r.metrics.observe("webhooks", start, &err)
+		if err != nil {
+			logger.Error("failed to fetch webhooks", err, lager.Data{"listID": list.ID})
+			return err
+		}
+
+		registered := false
+		for _, w := range existing {
+			switch {
+			case w.URL == callbackURL:
+				registered = true
+			case strings.HasPrefix(w.URL, callbackPrefix):
+				logger.Debug("deleting stale webhook", lager.Data{"listID": list.ID, "webhookID": w.ID})
+				start := time.Now()
+				err := client.DeleteWebhook(w)
+				r.metrics.observe("delete_webhook", start, &err)
+				if err != nil {
+					logger.Error("failed to delete stale webhook", err, lager.Data{"listID": list.ID})
+					return err
+				}
+			}
+		}
+
+		if registered {
+			continue
+		}
+
+		logger.Debug("creating webhook", lager.Data{"listID": list.ID})
+		start = time.Now()
+		_, err = client.CreateWebhook(list.ID, callbackURL, webhookProcessorType, "")
+		r.metrics.observe("create_webhook", start, &err)
+		if err != nil {
+			logger.Error("failed to create webhook", err, lager.Data{"listID": list.ID})
+			return err
+		}
+	}
+
+	return nil
+}